@@ -8,7 +8,13 @@ import (
 
 // allow go file to be run locally
 func main() {
-	err := internal.RunBot()
+	run := internal.RunBot
+	// `auth` gets the bot a spotify token before its first run
+	if len(os.Args) > 1 && os.Args[1] == "auth" {
+		run = internal.RunAuth
+	}
+
+	err := run()
 	if err != nil {
 		os.Exit(1)
 	}
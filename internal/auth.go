@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+)
+
+// authTimeout is how long the auth command waits for the user to approve the bot
+const authTimeout = 5 * time.Minute
+
+// RunAuth asks the user to authorise the bot with spotify and saves the token to the token file
+func RunAuth() error {
+	ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+	defer cancel()
+
+	logger := log.NewLogger()
+	cfg, err := config.LoadAuth()
+	if err != nil {
+		logger.RuntimeError(ctx, "failed to load config", err)
+		return err
+	}
+
+	tokenStore, err := newTokenStore(cfg.SpotifyTokenFile, cfg.SpotifyTokenKey)
+	if err != nil {
+		logger.RuntimeError(ctx, "failed to open token store", err)
+		return err
+	}
+
+	authorizer := spotify.NewAuthorizer(cfg.SpotifyClientId, cfg.SpotifyRedirectURI)
+	err = authorize(ctx, authorizer, tokenStore, openBrowser)
+	if err != nil {
+		logger.RuntimeError(ctx, "An error occurred while authorising the bot", err)
+		return err
+	}
+	logger.InfoContext(ctx, "saved spotify token", "file", cfg.SpotifyTokenFile)
+	return nil
+}
+
+func authorize(ctx context.Context, authorizer *spotify.Authorizer, store spotify.TokenStore, open func(string) error) error {
+	token, err := authorizer.Authorize(ctx, open)
+	if err != nil {
+		return errors.Wrap(err, "failed to authorise with spotify")
+	}
+	if err := store.Save(ctx, token); err != nil {
+		return errors.Wrap(err, "failed to save spotify token")
+	}
+	return nil
+}
+
+// openBrowser prints the authorize url and tries to open it in the user's browser
+func openBrowser(authorizeURL string) error {
+	fmt.Println("Approve the bot in your browser. If it doesn't open, visit:")
+	fmt.Println(authorizeURL)
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", authorizeURL)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", authorizeURL)
+	default:
+		cmd = exec.Command("xdg-open", authorizeURL)
+	}
+	// a machine without a browser can still use the url printed above
+	_ = cmd.Start()
+	return nil
+}
@@ -2,43 +2,123 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/musicbrainz"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/radio"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/trackcache"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
 )
 
+const (
+	// resolvedByLink means a radio song was resolved to the spotify track the ABC linked
+	resolvedByLink = "link"
+	// resolvedByCache means a radio song was resolved from the track cache
+	resolvedByCache = "cache"
+	// noCleanVersion marks a track cache entry for an explicit song without a clean version
+	noCleanVersion = "no-clean-version"
+)
+
 type Bot struct {
 	spotifyClient     spotify.Clienter
-	triplejClient     triplej.Clienter
+	radioSource       radio.Source
 	playlistSize      int
 	spotifyPlaylistId string
-	log               log.Log
+	dedupPolicy       string
+	skipExplicit      bool
+	// playlistOrder is which end of the playlist the newest songs go
+	playlistOrder string
+	// playlistDetails are used to find or create the playlist when spotifyPlaylistId is empty
+	playlistDetails spotify.PlaylistDetails
+	// description is the template the playlist description is rewritten from, nil to leave it alone
+	description *template.Template
+	timezone    *time.Location
+	station     triplej.Station
+	now         func() time.Time
+	// isrcLookup finds the ISRCs of songs the radio source doesn't give one for, nil when disabled
+	isrcLookup musicbrainz.Clienter
+	// trackCache remembers what recently played songs resolved to, nil when disabled
+	trackCache trackcache.Cache
+	// cacheScope keys the track cache by the settings songs are resolved with, so changing
+	// the market or match threshold doesn't reuse tracks resolved with the old ones
+	cacheScope string
+	log        log.Log
 }
 
-func NewBot(config config.Config, logger log.Log) *Bot {
-	spotifyClient := spotify.NewSpotifyClient(config.SpotifyClientId, config.SpotifyClientSecret, config.SpotifyRefreshToken)
-	return &Bot{
+// NewBot returns a bot for the config. Any spotify options are applied after those from the config.
+func NewBot(config config.Config, logger log.Log, opts ...spotify.Option) *Bot {
+	opts = append([]spotify.Option{spotify.WithMatchThreshold(config.MatchThreshold), spotify.WithMarket(config.SpotifyMarket), spotify.WithLogger(logger)}, opts...)
+	spotifyClient := spotify.NewSpotifyClient(config.SpotifyClientId, config.SpotifyClientSecret, config.SpotifyRefreshToken, opts...)
+	bot := &Bot{
 		spotifyClient:     spotifyClient,
-		triplejClient:     triplej.NewTiplejClient(),
+		radioSource:       newRadioSource(config),
 		playlistSize:      config.PlaylistSize,
 		spotifyPlaylistId: config.SpotifyPlaylistId,
+		dedupPolicy:       config.DedupPolicy,
+		skipExplicit:      config.SkipExplicit,
+		playlistOrder:     config.PlaylistOrder,
+		playlistDetails:   config.PlaylistDetails,
+		description:       config.PlaylistDescription,
+		timezone:          config.PlaylistTimezone,
+		station:           config.Station,
+		now:               time.Now,
 		log:               logger,
 	}
+	if config.MusicBrainzURL != "" {
+		bot.isrcLookup = musicbrainz.NewClient(musicbrainz.WithBaseURL(config.MusicBrainzURL))
+	}
+
+	// the cache only saves lookups, so the bot runs without one rather than not at all
+	trackCache, err := newTrackCache(config)
+	if err != nil {
+		logger.RuntimeError(context.Background(), "could not open track cache, resolving every song", err)
+	} else {
+		bot.trackCache = trackCache
+		bot.cacheScope = fmt.Sprintf("market:%s|threshold:%g", config.SpotifyMarket, config.MatchThreshold)
+	}
+	return bot
+}
+
+// Close releases the track cache
+func (b *Bot) Close() error {
+	if b.trackCache == nil {
+		return nil
+	}
+	return b.trackCache.Close()
+}
+
+func newRadioSource(cfg config.Config) radio.Source {
+	switch cfg.RadioSource {
+	case config.RadioSourceJSON:
+		return radio.NewJSONSource(cfg.RadioSourceURL, cfg.Station, cfg.RadioSourceFields)
+	case config.RadioSourceFile:
+		return radio.NewFileSource(cfg.RadioSourceFile, cfg.Station)
+	case config.RadioSourceICY:
+		return radio.NewICYSource(cfg.RadioSourceURL, cfg.Station)
+	default:
+		return triplej.NewTiplejClient(cfg.Station)
+	}
 }
 
 func (b *Bot) Run(ctx context.Context) error {
-	var mappedSongs []string
+	if err := b.ensurePlaylist(ctx); err != nil {
+		return errors.Wrap(err, "Error finding spotify playlist")
+	}
 
-	recentTriplejSongs, err := b.triplejClient.FetchSongsFromTriplejAPI(ctx, b.playlistSize)
+	recentTriplejSongs, err := b.radioSource.RecentSongs(ctx, b.playlistSize)
 	if err != nil {
-		return errors.Wrap(err, "Error fetching songs from TripleJ")
+		return errors.Wrap(err, "Error fetching songs from the radio source")
 	}
 
-	b.log.InfoContext(ctx, "Retrieved songs from triplej", "recentTriplejSongs", len(recentTriplejSongs))
+	b.log.InfoContext(ctx, "Retrieved songs from the radio source", "recentTriplejSongs", len(recentTriplejSongs))
 	if len(recentTriplejSongs) == 0 {
 		return errors.New("recentTriplejSongs contained 0 songs")
 	}
@@ -49,68 +129,256 @@ func (b *Bot) Run(ctx context.Context) error {
 	}
 	b.log.InfoContext(ctx, "tracks found in the current spotify playlist", "currentPlaylistSongs", len(currentPlaylistSongs))
 
-	lastPlayedSong, err := b.getTrackBySongNameAndArtist(ctx, recentTriplejSongs[0])
-	if err != nil {
+	// the up to date check uses the plays the dedup policy keeps, as keep-first can drop the
+	// newest play in favour of an earlier play of the same song
+	plays := dedupPlays(recentTriplejSongs, b.dedupPolicy)
+
+	// songs that can't be added never reach the playlist, so compare against the newest play
+	// that resolved
+	summary := &runSummary{}
+	var lastPlayedSong spotify.Track
+	for len(plays) > 0 {
+		lastPlayedSong, err = b.getTrackBySongNameAndArtist(ctx, plays[0])
+		if !unresolvable(err) {
+			break
+		}
+		summary.skip(plays[0], err)
+		plays = plays[1:]
+	}
+	switch {
+	case len(plays) == 0:
+		b.log.InfoContext(ctx, "none of the songs from the radio source can be added")
+		summary.log(ctx, b.log)
+		return nil
+	case err != nil:
 		return errors.Wrap(err, "Could not find last triplej song on spotify")
 	}
 
 	// exit if the last played song on the radio was also the most recently added song to this playlist
-	if len(currentPlaylistSongs) > 0 && lastPlayedSong.Uri == currentPlaylistSongs[len(currentPlaylistSongs)-1].Uri {
+	if newest, ok := newestTrack(currentPlaylistSongs, b.playlistOrder); ok && lastPlayedSong.Uri == newest.Uri {
 		b.log.InfoContext(ctx, "Playlist is already up to date with triplej")
+		b.describePlaylist(ctx, len(currentPlaylistSongs))
 		return nil
 	}
 	b.log.InfoContext(ctx, "🤖diff found between playlist and triplej. updating playlist...")
 
-	mappedSongs = append(mappedSongs, lastPlayedSong.Uri)
-	err = b.updateSpotifyPlaylist(ctx, recentTriplejSongs, currentPlaylistSongs, mappedSongs)
+	err = b.updateSpotifyPlaylist(ctx, plays, currentPlaylistSongs, lastPlayedSong, summary)
 	if err != nil {
 		return errors.Wrap(err, "Error updating spotify playlist")
 	}
 
+	summary.log(ctx, b.log)
+	b.describePlaylist(ctx, len(currentPlaylistSongs)-len(summary.removed)+len(summary.added))
 	return nil
 }
 
+// getTrackBySongNameAndArtist resolves the song to a spotify track, from the track cache when
+// the song was resolved recently.
 func (b *Bot) getTrackBySongNameAndArtist(ctx context.Context, song triplej.RadioSong) (spotify.Track, error) {
-	b.log.InfoContext(ctx, "looking up song", "song", song)
-	track, err := b.spotifyClient.GetTrackBySongNameAndArtist(ctx, song.Name, song.Artists)
+	if b.trackCache == nil {
+		match, err := b.resolveSong(ctx, song)
+		if err != nil {
+			return spotify.Track{}, err
+		}
+		return match.Track, nil
+	}
+
+	key := b.cacheKey(song)
+	entry, ok, err := b.trackCache.Get(ctx, key)
+	if err != nil {
+		b.log.RuntimeError(ctx, "could not read track cache", err)
+	}
+	switch {
+	case ok && entry.Method == noCleanVersion:
+		b.log.InfoContext(ctx, "no clean version found", "song", song.Name, "method", resolvedByCache)
+		return spotify.Track{}, errExplicit
+	case ok:
+		b.log.InfoContext(ctx, "resolved song", "song", song.Name, "track", entry.Track.String(), "method", resolvedByCache, "resolvedBy", entry.Method)
+		return entry.Track, nil
+	}
+
+	match, err := b.resolveSong(ctx, song)
+	switch {
+	case errors.Is(err, errExplicit):
+		// remember there is no clean version, so it isn't searched for again every run
+		entry = trackcache.Entry{Track: match.Track, Method: noCleanVersion, ResolvedAt: b.now()}
+	case err != nil:
+		return spotify.Track{}, err
+	default:
+		entry = trackcache.Entry{Track: match.Track, Confidence: match.Confidence, Method: match.Method, ResolvedAt: b.now()}
+	}
+	if err := b.trackCache.Put(ctx, key, entry); err != nil {
+		b.log.RuntimeError(ctx, "could not write track cache", err)
+	}
 	if err != nil {
-		return spotify.Track{}, errors.Wrap(err, "failed to get track")
+		return spotify.Track{}, err
 	}
-	return track, nil
+	return match.Track, nil
 }
 
-func (b *Bot) updateSpotifyPlaylist(ctx context.Context, triplejSongs []triplej.RadioSong, SpotifySongs []spotify.Track, songsToAdd []string) error {
-	var songsToRemove []spotify.Track
+// cacheKey identifies the song in the track cache. Songs resolve differently in other markets,
+// with another match threshold or when explicit tracks are swapped for clean ones, so each of
+// those settings gets its own entries.
+func (b *Bot) cacheKey(song triplej.RadioSong) string {
+	key := titleKey(song)
+	if song.Id != "" {
+		key = "arid:" + song.Id + "|" + key
+	}
+	if b.cacheScope != "" {
+		key = b.cacheScope + "|" + key
+	}
+	if b.skipExplicit {
+		key += "|clean"
+	}
+	return key
+}
 
-	for index, song := range triplejSongs[1:] {
-		// skip if duplicate songs were returned by the triplej API
-		// Note: in this context as we have sliced the first item off the list,
-		// the index is actually 1 less than you expect.
-		if song.Id == triplejSongs[index].Id {
-			continue
+// resolveSong resolves the song to a spotify track. When explicit songs are skipped, an
+// explicit track is swapped for a clean version of the song, or errExplicit is returned if
+// there isn't one.
+func (b *Bot) resolveSong(ctx context.Context, song triplej.RadioSong) (spotify.Match, error) {
+	resolved, err := b.resolveTrack(ctx, song)
+	if err != nil || !b.skipExplicit || !resolved.Track.Explicit {
+		return resolved, err
+	}
+
+	match, err := b.spotifyClient.MatchTrack(ctx, spotify.TrackQuery{Name: song.Name, Artists: song.Artists, Duration: song.Duration, CleanOnly: true})
+	if err != nil {
+		b.log.InfoContext(ctx, "no clean version found", "song", song.Name, "track", resolved.Track.String(), "error", err.Error())
+		return resolved, errExplicit
+	}
+	b.log.InfoContext(ctx, "swapped explicit track for a clean version", "song", song.Name, "track", match.Track.String(), "confidence", match.Confidence)
+	return match, nil
+}
+
+func (b *Bot) resolveTrack(ctx context.Context, song triplej.RadioSong) (spotify.Match, error) {
+	b.log.InfoContext(ctx, "looking up song", "song", song.Name, "artists", song.Artists)
+
+	// prefer the spotify track the ABC has linked, as it avoids a search that may pick the wrong track
+	if trackId := song.SpotifyTrackId(); trackId != "" {
+		track, err := b.spotifyClient.GetTrackById(ctx, trackId)
+		if err == nil {
+			b.log.InfoContext(ctx, "resolved song", "song", song.Name, "track", track.String(), "method", resolvedByLink)
+			return spotify.Match{Track: track, Confidence: 1, Method: resolvedByLink}, nil
 		}
+		b.log.RuntimeError(ctx, "could not resolve ABC spotify link, falling back to search", err)
+	}
 
-		tempSong, err := b.getTrackBySongNameAndArtist(ctx, song)
-		if err != nil {
-			continue
+	// an ISRC identifies the exact recording, so it is trusted over a search
+	if isrc := b.lookupISRC(ctx, song); isrc != "" {
+		match, err := b.spotifyClient.MatchISRC(ctx, isrc)
+		if err == nil {
+			b.log.InfoContext(ctx, "resolved song", "song", song.Name, "track", match.Track.String(), "method", match.Method, "isrc", isrc)
+			return match, nil
+		}
+		b.log.RuntimeError(ctx, "could not resolve ISRC, falling back to search", err)
+	}
+
+	match, err := b.spotifyClient.MatchTrack(ctx, spotify.TrackQuery{Name: song.Name, Artists: song.Artists, Duration: song.Duration})
+	if err != nil {
+		return spotify.Match{}, errors.Wrap(err, "failed to get track")
+	}
+	b.log.InfoContext(ctx, "resolved song", "song", song.Name, "track", match.Track.String(), "method", match.Method, "confidence", match.Confidence)
+	return match, nil
+}
+
+// lookupISRC returns the ISRC of the song, asking the ISRC lookup for it when the radio
+// source didn't provide one. An empty string means the ISRC isn't known.
+func (b *Bot) lookupISRC(ctx context.Context, song triplej.RadioSong) string {
+	if song.ISRC != "" {
+		return song.ISRC
+	}
+	recordingId := song.MusicBrainzRecordingId()
+	if b.isrcLookup == nil || recordingId == "" {
+		return ""
+	}
+
+	isrcs, err := b.isrcLookup.LookupISRCs(ctx, recordingId)
+	if err != nil {
+		b.log.RuntimeError(ctx, "could not look up ISRC", err)
+		return ""
+	}
+	if len(isrcs) == 0 {
+		return ""
+	}
+	return isrcs[0]
+}
+
+// updateSpotifyPlaylist adds the deduped plays, newest first, that are newer than the newest
+// track in the playlist. lastPlayedSong is what the first play resolved to.
+func (b *Bot) updateSpotifyPlaylist(ctx context.Context, plays []triplej.RadioSong, SpotifySongs []spotify.Track, lastPlayedSong spotify.Track, summary *runSummary) error {
+	var (
+		songsToAdd    []string
+		songsToRemove []spotify.Track
+		// the tracks being added by uri, for the run summary
+		tracksToAdd = make(map[string]spotify.Track)
+		// songs already in the playlist that are being moved up to their latest play
+		replayedSongs = make(map[string]bool)
+		inPlaylist    = make(map[string]bool, len(SpotifySongs))
+	)
+	for _, track := range SpotifySongs {
+		inPlaylist[track.Uri] = true
+	}
+	newest, hasNewest := newestTrack(SpotifySongs, b.playlistOrder)
+
+	for i, song := range plays {
+		tempSong := lastPlayedSong
+		// the last played song has already been looked up
+		if i > 0 {
+			var err error
+			tempSong, err = b.getTrackBySongNameAndArtist(ctx, song)
+			if err != nil {
+				summary.skip(song, err)
+				continue
+			}
 		}
 
-		if len(SpotifySongs) > 0 && SpotifySongs[len(SpotifySongs)-1].Uri == tempSong.Uri {
+		if hasNewest && newest.Uri == tempSong.Uri {
 			break
 		}
 
-		if len(tempSong.Uri) > 0 {
-			// prepend item to slice
-			songsToAdd = append([]string{tempSong.Uri}, songsToAdd...)
+		if len(tempSong.Uri) == 0 {
+			continue
+		}
+
+		// different recordings can resolve to the same track, so dedup again on what will be added
+		if b.dedupPolicy != config.DedupAllowRepeats {
+			if index := slices.Index(songsToAdd, tempSong.Uri); index >= 0 {
+				if b.dedupPolicy != config.DedupKeepFirst {
+					continue
+				}
+				// this play is older, so it replaces the one already queued
+				songsToAdd = slices.Delete(songsToAdd, index, index+1)
+			}
+			if inPlaylist[tempSong.Uri] {
+				if b.dedupPolicy == config.DedupKeepFirst {
+					continue
+				}
+				replayedSongs[tempSong.Uri] = true
+			}
+		}
+
+		// prepend item to slice
+		songsToAdd = append([]string{tempSong.Uri}, songsToAdd...)
+		tracksToAdd[tempSong.Uri] = tempSong
+	}
+
+	// replayed songs are removed from their old position before being added again
+	var remainingSongs []spotify.Track
+	for _, track := range SpotifySongs {
+		if replayedSongs[track.Uri] {
+			songsToRemove = append(songsToRemove, track)
+		} else {
+			remainingSongs = append(remainingSongs, track)
 		}
 	}
 
 	// Calculate the number of songs to remove
-	numToRemove := len(songsToAdd) + len(SpotifySongs) - b.playlistSize
+	numToRemove := len(songsToAdd) + len(remainingSongs) - b.playlistSize
 
-	// If we need to remove songs, slice the SpotifySongs slice to get the songs to remove
+	// If we need to remove songs, slice the remaining songs to get the oldest ones to remove
 	if numToRemove > 0 {
-		songsToRemove = append(songsToRemove, SpotifySongs[:numToRemove]...)
+		songsToRemove = append(songsToRemove, oldestTracks(remainingSongs, numToRemove, b.playlistOrder)...)
 	}
 
 	if len(songsToRemove) > 0 {
@@ -119,13 +387,25 @@ func (b *Bot) updateSpotifyPlaylist(ctx context.Context, triplejSongs []triplej.
 		if err != nil {
 			return errors.Wrap(err, "Error removing songs from playlist")
 		}
+		for _, track := range songsToRemove {
+			summary.removed = append(summary.removed, track.String())
+		}
 	}
 
-	b.log.InfoContext(ctx, "adding songs to playlist...", "songsToAdd", len(songsToAdd))
-	err := b.spotifyClient.AddSongsToPlaylist(ctx, songsToAdd, b.spotifyPlaylistId)
+	b.log.InfoContext(ctx, "adding songs to playlist...", "songsToAdd", len(songsToAdd), "order", b.playlistOrder)
+	songsToAdd = inPlaylistOrder(songsToAdd, b.playlistOrder)
+	var err error
+	if b.playlistOrder == config.OrderNewestFirst {
+		err = b.spotifyClient.InsertSongsIntoPlaylist(ctx, songsToAdd, b.spotifyPlaylistId, 0)
+	} else {
+		err = b.spotifyClient.AddSongsToPlaylist(ctx, songsToAdd, b.spotifyPlaylistId)
+	}
 	if err != nil {
 		return errors.Wrap(err, "Error adding songs to playlist")
 	}
+	for _, uri := range songsToAdd {
+		summary.added = append(summary.added, tracksToAdd[uri].String())
+	}
 
 	return nil
 }
@@ -2,17 +2,23 @@ package internal
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
+	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+	mock_musicbrainz "github.com/JamesBLewis/triplej-playlist-generator/pkg/musicbrainz/mocks"
+	mock_radio "github.com/JamesBLewis/triplej-playlist-generator/pkg/radio/mocks"
 	mock_spotify "github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify/mocks"
 
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/trackcache"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
-	mock_triplej "github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej/mocks"
 )
 
 func TestBot_Run(t *testing.T) {
@@ -25,7 +31,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("empty playlist", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -33,7 +39,7 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      30,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
@@ -61,11 +67,11 @@ func TestBot_Run(t *testing.T) {
 				Name: "oldest song",
 			},
 		}
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
 		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[0].Name, triplejSongs[0].Artists).Return(spotify.Track{Uri: "uri:song0"}, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[1].Name, triplejSongs[1].Artists).Return(spotify.Track{Uri: "uri:song1"}, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[2].Name, triplejSongs[2].Artists).Return(spotify.Track{Uri: "uri:song2"}, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:song0"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:song1"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:song2"), nil)
 
 		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:song2", "uri:song1", "uri:song0"}, b.spotifyPlaylistId).Return(nil)
 
@@ -76,7 +82,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("full playlist", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -84,7 +90,7 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      3,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
@@ -114,11 +120,11 @@ func TestBot_Run(t *testing.T) {
 				Name: "oldest song",
 			},
 		}
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
 		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[0].Name, triplejSongs[0].Artists).Return(spotify.Track{Uri: "uri:song0"}, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[1].Name, triplejSongs[1].Artists).Return(spotify.Track{Uri: "uri:song1"}, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[2].Name, triplejSongs[2].Artists).Return(spotify.Track{Uri: "uri:song2"}, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:song0"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:song1"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:song2"), nil)
 
 		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:song2", "uri:song1", "uri:song0"}, b.spotifyPlaylistId).Return(nil)
 
@@ -131,7 +137,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("duplicate triplej songs", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -139,7 +145,7 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      3,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
@@ -172,10 +178,10 @@ func TestBot_Run(t *testing.T) {
 				},
 			},
 		}
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
 		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[0].Name, triplejSongs[0].Artists).Return(spotify.Track{Uri: "uri:song0"}, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[1].Name, triplejSongs[1].Artists).Return(spotify.Track{Uri: "uri:song1"}, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:song0"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:song1"), nil)
 
 		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:song1", "uri:song0"}, b.spotifyPlaylistId).Return(nil)
 
@@ -188,7 +194,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("existing playlist larger current playlistSize value", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -196,7 +202,7 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      1,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
@@ -214,9 +220,9 @@ func TestBot_Run(t *testing.T) {
 				},
 			},
 		}
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
 		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[0].Name, triplejSongs[0].Artists).Return(spotify.Track{Uri: "uri:latestsong"}, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:latestsong"), nil)
 		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:latestsong"}, b.spotifyPlaylistId).Return(nil)
 
 		mockSpotifyClient.EXPECT().RemoveSongsFromPlaylist(args.ctx, currentTracks, b.spotifyPlaylistId)
@@ -228,7 +234,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("existing playlist smaller than current playlistSize value", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -236,7 +242,7 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      4,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
@@ -266,10 +272,10 @@ func TestBot_Run(t *testing.T) {
 				Name: "oldest song",
 			},
 		}
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
 		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[0].Name, triplejSongs[0].Artists).Return(spotify.Track{Uri: "uri:latestsong"}, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[1].Name, triplejSongs[1].Artists).Return(spotify.Track{Uri: "uri:oldSong2"}, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:latestsong"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:oldSong2"), nil)
 
 		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:latestsong"}, b.spotifyPlaylistId).Return(nil)
 
@@ -280,7 +286,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("up to date playlist", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -288,7 +294,7 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      3,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
@@ -315,9 +321,510 @@ func TestBot_Run(t *testing.T) {
 				Name: "oldest song",
 			},
 		}
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
 		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
-		mockSpotifyClient.EXPECT().GetTrackBySongNameAndArtist(args.ctx, triplejSongs[0].Name, triplejSongs[0].Artists).Return(spotify.Track{Uri: "uri:song0"}, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:song0"), nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("newest first playlist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			playlistOrder:     config.OrderNewestFirst,
+			log:               log.NewLogger(),
+		}
+
+		// the newest song is at the top, so the oldest songs are at the bottom
+		currentTracks := []spotify.Track{{Uri: "uri:song0"}, {Uri: "uri:oldSong2"}, {Uri: "uri:oldSong1"}}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", Name: "latest song"},
+			{Id: "1", Name: "middle song"},
+			{Id: "0", Name: "oldest song"},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:song2"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:song1"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:song0"), nil)
+
+		mockSpotifyClient.EXPECT().RemoveSongsFromPlaylist(args.ctx, currentTracks[1:], b.spotifyPlaylistId)
+		mockSpotifyClient.EXPECT().InsertSongsIntoPlaylist(args.ctx, []string{"uri:song2", "uri:song1"}, b.spotifyPlaylistId, 0).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("up to date newest first playlist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			playlistOrder:     config.OrderNewestFirst,
+			log:               log.NewLogger(),
+		}
+
+		currentTracks := []spotify.Track{{Uri: "uri:song0"}, {Uri: "uri:oldSong2"}, {Uri: "uri:oldSong1"}}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{{Id: "0", Name: "latest song"}}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:song0"), nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("songs linked to spotify by the ABC", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			log:               log.NewLogger(),
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{
+				Id:      "1",
+				Name:    "linked song",
+				Artists: []string{"only artist"},
+				Links:   []triplej.Link{{Provider: "spotify", Url: "https://open.spotify.com/track/4Y2W4bRg1nZLkvZgZpFbTw"}},
+			},
+			{
+				Id:      "0",
+				Name:    "badly linked song",
+				Artists: []string{"only artist"},
+				Links:   []triplej.Link{{Provider: "spotify", Url: "https://open.spotify.com/track/0VjIjW4GlUZAMYd2vXMi3b"}},
+			},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().GetTrackById(args.ctx, "4Y2W4bRg1nZLkvZgZpFbTw").Return(spotify.Track{Uri: "spotify:track:4Y2W4bRg1nZLkvZgZpFbTw"}, nil)
+		mockSpotifyClient.EXPECT().GetTrackById(args.ctx, "0VjIjW4GlUZAMYd2vXMi3b").Return(spotify.Track{}, errors.New("invalid status code: 404"))
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:searched"), nil)
+
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:searched", "spotify:track:4Y2W4bRg1nZLkvZgZpFbTw"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("songs resolved by ISRC", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+		mockISRCLookup := mock_musicbrainz.NewMockClienter(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			isrcLookup:        mockISRCLookup,
+			log:               log.NewLogger(),
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", Name: "song with isrc", Artists: []string{"artist"}, ISRC: "AUAB12400001"},
+			{
+				Id:      "1",
+				Name:    "song on musicbrainz",
+				Artists: []string{"artist"},
+				Links:   []triplej.Link{{Provider: "musicbrainz", Url: "https://musicbrainz.org/recording/mbid1", IdComponent: "mbid1"}},
+			},
+			{Id: "0", Name: "song with unknown isrc", Artists: []string{"artist"}, ISRC: "AUAB12400000"},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().MatchISRC(args.ctx, "AUAB12400001").Return(isrcMatch("uri:isrc"), nil)
+		mockISRCLookup.EXPECT().LookupISRCs(args.ctx, "mbid1").Return([]string{"AUAB12400002"}, nil)
+		mockSpotifyClient.EXPECT().MatchISRC(args.ctx, "AUAB12400002").Return(isrcMatch("uri:musicbrainz"), nil)
+		mockSpotifyClient.EXPECT().MatchISRC(args.ctx, "AUAB12400000").Return(spotify.Match{}, spotify.ErrNoMatch)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:searched"), nil)
+
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:searched", "uri:musicbrainz", "uri:isrc"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("explicit songs are swapped for clean versions or skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+		logger := &recordingLogger{Log: log.NewLogger()}
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			skipExplicit:      true,
+			log:               logger,
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", Name: "explicit only", Artists: []string{"artist"}},
+			{Id: "1", Name: "has clean version", Artists: []string{"artist"}},
+			{Id: "0", Name: "clean song", Artists: []string{"artist"}},
+		}
+		cleanQuery := func(song triplej.RadioSong) spotify.TrackQuery {
+			query := trackQuery(song)
+			query.CleanOnly = true
+			return query
+		}
+		explicitMatch := func(uri string) spotify.Match {
+			match := searchMatch(uri)
+			match.Track.Explicit = true
+			return match
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(explicitMatch("uri:explicitOnly"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, cleanQuery(triplejSongs[0])).Return(spotify.Match{}, spotify.ErrNoMatch)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(explicitMatch("uri:explicit"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, cleanQuery(triplejSongs[1])).Return(searchMatch("uri:clean"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:cleanSong"), nil)
+
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:cleanSong", "uri:clean"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+
+		summary := logger.attrs("run summary")
+		require.Equal(t, []string{"uri:cleanSong", "uri:clean"}, summary["added"])
+		require.Equal(t, []string{"artist - explicit only: " + errExplicit.Error()}, summary["skipped"])
+	})
+
+	t.Run("up to date playlist behind an explicit song without a clean version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			skipExplicit:      true,
+			log:               log.NewLogger(),
+		}
+
+		currentTracks := []spotify.Track{{Uri: "uri:oldSong1"}, {Uri: "uri:cleanSong"}}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "1", Name: "explicit only", Artists: []string{"artist"}},
+			{Id: "0", Name: "clean song", Artists: []string{"artist"}},
+		}
+		cleanQuery := trackQuery(triplejSongs[0])
+		cleanQuery.CleanOnly = true
+		explicitMatch := searchMatch("uri:explicitOnly")
+		explicitMatch.Track.Explicit = true
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(explicitMatch, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, cleanQuery).Return(spotify.Match{}, spotify.ErrNoMatch)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:cleanSong"), nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("songs without a confident match are skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			log:               log.NewLogger(),
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", Name: "latest song", Artists: []string{"artist"}},
+			{Id: "1", Name: "obscure song", Artists: []string{"unknown artist"}},
+			{Id: "0", Name: "oldest song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:latest"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(spotify.Match{}, errors.Wrap(spotify.ErrNoMatch, "best match was a karaoke version"))
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:oldest"), nil)
+
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:oldest", "uri:latest"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("newest song without a confident match is skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			log:               log.NewLogger(),
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", Name: "station ident", Artists: []string{"triple j"}},
+			{Id: "1", Name: "newer song", Artists: []string{"artist"}},
+			{Id: "0", Name: "oldest song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(spotify.Match{}, errors.Wrap(spotify.ErrNoMatch, "could not find track"))
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:newer"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:oldest"), nil)
+
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:oldest", "uri:newer"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("newest song unplayable in the market is skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			log:               log.NewLogger(),
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", Name: "unplayable song", Artists: []string{"artist"}},
+			{Id: "1", Name: "newer song", Artists: []string{"artist"}},
+			{Id: "0", Name: "oldest song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(spotify.Match{}, errors.Wrap(spotify.ErrUnplayable, "unplayable song artist in AU"))
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:newer"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:oldest"), nil)
+
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:oldest", "uri:newer"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("newest song failing to look up fails the run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			log:               log.NewLogger(),
+		}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "1", Name: "latest song", Artists: []string{"artist"}},
+			{Id: "0", Name: "oldest song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return([]spotify.Track(nil), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(spotify.Match{}, errors.New("invalid status code: 500"))
+
+		err := b.Run(args.ctx)
+		require.ErrorContains(t, err, "Could not find last triplej song on spotify")
+	})
+
+	t.Run("replayed song already in playlist keeps latest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			dedupPolicy:       config.DedupKeepLatest,
+			log:               log.NewLogger(),
+		}
+
+		currentTracks := []spotify.Track{{Uri: "uri:oldSong1"}, {Uri: "uri:replayed"}, {Uri: "uri:oldSong3"}}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "3", Name: "latest song", Artists: []string{"artist"}},
+			{Id: "2", Name: "replayed song", Artists: []string{"artist"}},
+			{Id: "1", Name: "song played earlier in the window", Artists: []string{"artist"}},
+			{Id: "2", Name: "replayed song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:latest"), nil)
+		// the replayed song is only looked up once
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:replayed"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:oldSong3"), nil)
+
+		// the replayed song moves from the middle of the playlist to its latest play
+		mockSpotifyClient.EXPECT().RemoveSongsFromPlaylist(args.ctx, []spotify.Track{{Uri: "uri:replayed"}, {Uri: "uri:oldSong1"}}, b.spotifyPlaylistId)
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:replayed", "uri:latest"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("replayed song already in playlist keeps first", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			dedupPolicy:       config.DedupKeepFirst,
+			log:               log.NewLogger(),
+		}
+
+		currentTracks := []spotify.Track{{Uri: "uri:oldSong1"}, {Uri: "uri:replayed"}, {Uri: "uri:oldSong3"}}
+
+		// mock logic
+		triplejSongs := []triplej.RadioSong{
+			{Id: "3", Name: "latest song", Artists: []string{"artist"}},
+			{Id: "2", Name: "replayed song", Artists: []string{"artist"}},
+			{Id: "1", Name: "oldest song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[0])).Return(searchMatch("uri:latest"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:replayed"), nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[2])).Return(searchMatch("uri:oldSong3"), nil)
+
+		// the replayed song stays where it is
+		mockSpotifyClient.EXPECT().RemoveSongsFromPlaylist(args.ctx, []spotify.Track{{Uri: "uri:oldSong1"}}, b.spotifyPlaylistId)
+		mockSpotifyClient.EXPECT().AddSongsToPlaylist(args.ctx, []string{"uri:latest"}, b.spotifyPlaylistId).Return(nil)
+
+		err := b.Run(args.ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("up to date keep first playlist with a replayed song", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
+
+		args := args{
+			testCtx,
+		}
+
+		b := &Bot{
+			spotifyClient:     mockSpotifyClient,
+			radioSource:       mockRadioSource,
+			playlistSize:      3,
+			spotifyPlaylistId: "1234",
+			dedupPolicy:       config.DedupKeepFirst,
+			log:               log.NewLogger(),
+		}
+
+		currentTracks := []spotify.Track{{Uri: "uri:oldSong1"}, {Uri: "uri:replayed"}, {Uri: "uri:latest"}}
+
+		// the newest play is a replay, so keep-first keeps its earlier play instead
+		triplejSongs := []triplej.RadioSong{
+			{Id: "2", PlayId: "play3", Name: "replayed song", Artists: []string{"artist"}},
+			{Id: "3", PlayId: "play2", Name: "latest song", Artists: []string{"artist"}},
+			{Id: "2", PlayId: "play1", Name: "replayed song", Artists: []string{"artist"}},
+		}
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return(triplejSongs, nil)
+		mockSpotifyClient.EXPECT().GetCurrentPlaylist(args.ctx, b.spotifyPlaylistId).Return(currentTracks, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(args.ctx, trackQuery(triplejSongs[1])).Return(searchMatch("uri:latest"), nil)
 
 		err := b.Run(args.ctx)
 		require.NoError(t, err)
@@ -326,7 +833,7 @@ func TestBot_Run(t *testing.T) {
 	t.Run("empty response from triplej", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
-		mockTriplejClient := mock_triplej.NewMockClienter(ctrl)
+		mockRadioSource := mock_radio.NewMockSource(ctrl)
 
 		args := args{
 			testCtx,
@@ -334,15 +841,145 @@ func TestBot_Run(t *testing.T) {
 
 		b := &Bot{
 			spotifyClient:     mockSpotifyClient,
-			triplejClient:     mockTriplejClient,
+			radioSource:       mockRadioSource,
 			playlistSize:      3,
 			spotifyPlaylistId: "1234",
 			log:               log.NewLogger(),
 		}
 
-		mockTriplejClient.EXPECT().FetchSongsFromTriplejAPI(args.ctx, b.playlistSize).Return([]triplej.RadioSong{}, nil)
+		mockRadioSource.EXPECT().RecentSongs(args.ctx, b.playlistSize).Return([]triplej.RadioSong{}, nil)
 
 		err := b.Run(args.ctx)
 		require.Error(t, err)
 	})
 }
+
+func trackQuery(song triplej.RadioSong) spotify.TrackQuery {
+	return spotify.TrackQuery{Name: song.Name, Artists: song.Artists, Duration: song.Duration}
+}
+
+func searchMatch(uri string) spotify.Match {
+	return spotify.Match{Track: spotify.Track{Uri: uri}, Confidence: 1, Method: spotify.MatchMethodSearch}
+}
+
+func isrcMatch(uri string) spotify.Match {
+	return spotify.Match{Track: spotify.Track{Uri: uri}, Confidence: 1, Method: spotify.MatchMethodISRC}
+}
+
+func TestBot_getTrackBySongNameAndArtist_Cache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 9, 14, 8, 0, 0, 0, time.UTC)
+	song := triplej.RadioSong{Id: "mt8dZ1N3Qa", Name: "Tongue Tied", Artists: []string{"Marshmello", "YUNGBLUD"}}
+	track := spotify.Track{Uri: "uri:tongueTied", Name: "Tongue Tied"}
+
+	t.Run("miss stores the resolved track", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		cache := trackcache.NewMemoryCache(trackcache.WithClock(func() time.Time { return now }))
+		b := &Bot{spotifyClient: mockSpotifyClient, trackCache: cache, now: func() time.Time { return now }, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().MatchTrack(ctx, gomock.Any()).Return(spotify.Match{Track: track, Confidence: 0.9, Method: spotify.MatchMethodSearch}, nil)
+
+		got, err := b.getTrackBySongNameAndArtist(ctx, song)
+		require.NoError(t, err)
+		require.Equal(t, track, got)
+
+		entry, ok, err := cache.Get(ctx, b.cacheKey(song))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, trackcache.Entry{Track: track, Confidence: 0.9, Method: spotify.MatchMethodSearch, ResolvedAt: now}, entry)
+	})
+
+	t.Run("hit skips spotify", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		cache := trackcache.NewMemoryCache(trackcache.WithClock(func() time.Time { return now }))
+		b := &Bot{spotifyClient: mock_spotify.NewMockClienter(ctrl), trackCache: cache, now: func() time.Time { return now }, log: log.NewLogger()}
+		require.NoError(t, cache.Put(ctx, b.cacheKey(song), trackcache.Entry{Track: track, Confidence: 1, Method: resolvedByLink, ResolvedAt: now.Add(-time.Hour)}))
+
+		got, err := b.getTrackBySongNameAndArtist(ctx, song)
+		require.NoError(t, err)
+		require.Equal(t, track, got)
+	})
+
+	t.Run("clean tracks are cached separately", func(t *testing.T) {
+		b := &Bot{}
+		key := b.cacheKey(song)
+		b.skipExplicit = true
+		require.NotEqual(t, key, b.cacheKey(song))
+	})
+
+	t.Run("tracks are cached per market and match threshold", func(t *testing.T) {
+		cfg := config.Config{TrackCache: config.TrackCacheMemory, TrackCacheTTL: time.Hour, SpotifyMarket: "AU", MatchThreshold: 0.6}
+		b := NewBot(cfg, log.NewLogger())
+		key := b.cacheKey(song)
+
+		cfg.SpotifyMarket = "NZ"
+		require.NotEqual(t, key, NewBot(cfg, log.NewLogger()).cacheKey(song))
+
+		cfg.SpotifyMarket = "AU"
+		cfg.MatchThreshold = 0.8
+		require.NotEqual(t, key, NewBot(cfg, log.NewLogger()).cacheKey(song))
+	})
+
+	t.Run("runs without a cache that can't be opened", func(t *testing.T) {
+		cfg := config.Config{TrackCache: config.TrackCacheFile, TrackCacheFile: filepath.Join(t.TempDir(), "missing", "track-cache.db"), TrackCacheTTL: time.Hour}
+		b := NewBot(cfg, log.NewLogger())
+		require.Nil(t, b.trackCache)
+		require.NoError(t, b.Close())
+	})
+
+	t.Run("songs without a clean version are remembered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		cache := trackcache.NewMemoryCache(trackcache.WithClock(func() time.Time { return now }))
+		b := &Bot{spotifyClient: mockSpotifyClient, trackCache: cache, skipExplicit: true, now: func() time.Time { return now }, log: log.NewLogger()}
+
+		explicit := spotify.Match{Track: spotify.Track{Uri: "uri:explicit", Explicit: true}, Confidence: 1, Method: spotify.MatchMethodSearch}
+		mockSpotifyClient.EXPECT().MatchTrack(ctx, gomock.Any()).Return(explicit, nil)
+		mockSpotifyClient.EXPECT().MatchTrack(ctx, gomock.Any()).Return(spotify.Match{}, spotify.ErrNoMatch)
+
+		_, err := b.getTrackBySongNameAndArtist(ctx, song)
+		require.ErrorIs(t, err, errExplicit)
+
+		// the second lookup comes from the cache, so spotify isn't searched again
+		_, err = b.getTrackBySongNameAndArtist(ctx, song)
+		require.ErrorIs(t, err, errExplicit)
+	})
+
+	t.Run("unresolved songs aren't cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		cache := trackcache.NewMemoryCache()
+		b := &Bot{spotifyClient: mockSpotifyClient, trackCache: cache, now: func() time.Time { return now }, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().MatchTrack(ctx, gomock.Any()).Return(spotify.Match{}, errors.New("no match"))
+
+		_, err := b.getTrackBySongNameAndArtist(ctx, song)
+		require.Error(t, err)
+		_, ok, err := cache.Get(ctx, b.cacheKey(song))
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
+
+// recordingLogger keeps the attributes of each info message so tests can check what was logged
+type recordingLogger struct {
+	log.Log
+	messages map[string]map[string]any
+}
+
+func (l *recordingLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	if l.messages == nil {
+		l.messages = make(map[string]map[string]any)
+	}
+	attrs := make(map[string]any)
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[args[i].(string)] = args[i+1]
+	}
+	l.messages[msg] = attrs
+	l.Log.InfoContext(ctx, msg, args...)
+}
+
+func (l *recordingLogger) attrs(msg string) map[string]any {
+	return l.messages[msg]
+}
@@ -1,13 +1,75 @@
 package config
 
 import (
+	"encoding/base64"
 	"log"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	// embed zoneinfo so PLAYLIST_TIMEZONE works on hosts without tzdata, not just in the container image
+	_ "time/tzdata"
 
 	"github.com/pkg/errors"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/radio"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/trackcache"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+// where the bot reads plays from
+const (
+	RadioSourceABC  = "abc"
+	RadioSourceJSON = "json"
+	RadioSourceFile = "file"
+	RadioSourceICY  = "icy"
+)
+
+// how songs played more than once are handled
+const (
+	// DedupKeepLatest keeps only the most recent play of a song, moving it up the playlist when replayed
+	DedupKeepLatest = "keep-latest"
+	// DedupKeepFirst keeps only the earliest play of a song, leaving it where it already is in the playlist
+	DedupKeepFirst = "keep-first"
+	// DedupAllowRepeats adds every play of a song
+	DedupAllowRepeats = "allow-repeats"
 )
 
+// which end of the playlist the newest songs go
+const (
+	// OrderOldestFirst appends new songs, so the newest song is at the bottom of the playlist
+	OrderOldestFirst = "oldest-first"
+	// OrderNewestFirst inserts new songs at the top, so the newest song is first
+	OrderNewestFirst = "newest-first"
+)
+
+// where resolved songs are cached between runs
+const (
+	// TrackCacheFile keeps resolved songs in a file, so later runs don't search for them again
+	TrackCacheFile = "file"
+	// TrackCacheMemory only keeps resolved songs for the run, the default
+	TrackCacheMemory = "memory"
+	// TrackCacheOff resolves every song every time
+	TrackCacheOff = "off"
+)
+
+const (
+	// DefaultPlaylistDescription is the template the playlist description is rewritten from after each run
+	DefaultPlaylistDescription = `Last updated {{.Updated.Format "15:04 MST"}} — {{.Songs}} songs from {{.Station}}`
+	// DefaultPlaylistTimezone is the timezone the playlist description gives times in
+	DefaultPlaylistTimezone = "Australia/Sydney"
+	// DefaultTokenFile is where the auth command saves the spotify token
+	DefaultTokenFile = "spotify-token.json"
+	// DefaultTrackCacheFile is where resolved songs are cached when TrackCache is TrackCacheFile
+	DefaultTrackCacheFile = "track-cache.db"
+)
+
+// marketPattern matches an ISO 3166-1 alpha-2 country code
+var marketPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
 type Config struct {
 	SpotifyClientId     string
 	SpotifyClientSecret string
@@ -15,6 +77,44 @@ type Config struct {
 	SpotifyRefreshToken string
 	SpotifyPlaylistId   string
 	PlaylistSize        int
+	Station             triplej.Station
+	RadioSource         string
+	RadioSourceURL      string
+	RadioSourceFile     string
+	RadioSourceFields   radio.FieldMapping
+	DedupPolicy         string
+	MatchThreshold      float64
+	SkipExplicit        bool
+	// SpotifyMarket is the country code tracks must be playable in
+	SpotifyMarket string
+	// MusicBrainzURL is the MusicBrainz compatible web service ISRCs are looked up from, empty to disable
+	MusicBrainzURL string
+	// PlaylistOrder is which end of the playlist the newest songs go, see OrderOldestFirst
+	PlaylistOrder string
+	// PlaylistDetails name the playlist found or created when SpotifyPlaylistId is empty
+	PlaylistDetails spotify.PlaylistDetails
+	// PlaylistDescription is rewritten after each run, nil to leave the description alone
+	PlaylistDescription *template.Template
+	// PlaylistTimezone is the timezone times in the description are given in
+	PlaylistTimezone *time.Location
+	// SpotifyTokenFile keeps the spotify tokens between runs, see the auth command
+	SpotifyTokenFile string
+	// SpotifyTokenKey encrypts the token file when set
+	SpotifyTokenKey []byte
+	// TrackCache is where resolved songs are cached, see TrackCacheFile
+	TrackCache string
+	// TrackCacheFile is the file resolved songs are cached in
+	TrackCacheFile string
+	// TrackCacheTTL is how long a resolved song is trusted before it is searched for again
+	TrackCacheTTL time.Duration
+}
+
+// AuthConfig is the config of the auth command
+type AuthConfig struct {
+	SpotifyClientId    string
+	SpotifyRedirectURI string
+	SpotifyTokenFile   string
+	SpotifyTokenKey    []byte
 }
 
 func Load() (Config, error) {
@@ -27,12 +127,151 @@ func Load() (Config, error) {
 		return Config{}, errors.Wrap(err, "PlaylistSize was invalid")
 	}
 
+	radioSource := strings.ToLower(os.Getenv("RADIO_SOURCE"))
+	if radioSource == "" {
+		radioSource = RadioSourceABC
+	}
+
+	var (
+		station      = triplej.Station(os.Getenv("RADIO_STATION"))
+		sourceFields radio.FieldMapping
+	)
+	switch radioSource {
+	case RadioSourceABC:
+		station, err = triplej.ParseStation(os.Getenv("RADIO_STATION"))
+		if err != nil {
+			return Config{}, errors.Wrap(err, "Station was invalid")
+		}
+	case RadioSourceJSON:
+		sourceFields, err = radio.ParseFieldMapping(os.Getenv("RADIO_SOURCE_FIELDS"))
+		if err != nil {
+			return Config{}, errors.Wrap(err, "RadioSourceFields was invalid")
+		}
+	}
+
+	matchThreshold := spotify.DefaultMatchThreshold
+	if value := os.Getenv("MATCH_THRESHOLD"); value != "" {
+		matchThreshold, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "MatchThreshold was invalid")
+		}
+	}
+
+	var skipExplicit bool
+	if value := os.Getenv("SKIP_EXPLICIT"); value != "" {
+		skipExplicit, err = strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "SkipExplicit was invalid")
+		}
+	}
+
+	playlistPublic := true
+	if value := os.Getenv("PLAYLIST_PUBLIC"); value != "" {
+		playlistPublic, err = strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "PlaylistPublic was invalid")
+		}
+	}
+
+	var playlistCollaborative bool
+	if value := os.Getenv("PLAYLIST_COLLABORATIVE"); value != "" {
+		playlistCollaborative, err = strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "PlaylistCollaborative was invalid")
+		}
+	}
+
+	playlistName := os.Getenv("PLAYLIST_NAME")
+	if playlistName == "" {
+		playlistName = station.DisplayName() + " recently played"
+	}
+
+	updateDescription := true
+	if value := os.Getenv("UPDATE_DESCRIPTION"); value != "" {
+		updateDescription, err = strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "UpdateDescription was invalid")
+		}
+	}
+
+	var playlistDescription *template.Template
+	if updateDescription {
+		text := os.Getenv("PLAYLIST_DESCRIPTION")
+		if text == "" {
+			text = DefaultPlaylistDescription
+		}
+		playlistDescription, err = template.New("description").Parse(text)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "PlaylistDescription was invalid")
+		}
+	}
+
+	timezone := os.Getenv("PLAYLIST_TIMEZONE")
+	if timezone == "" {
+		timezone = DefaultPlaylistTimezone
+	}
+	playlistTimezone, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "PlaylistTimezone was invalid")
+	}
+
+	tokenKey, err := loadTokenKey()
+	if err != nil {
+		return Config{}, err
+	}
+
+	trackCacheTTL := trackcache.DefaultTTL
+	if value := os.Getenv("TRACK_CACHE_TTL"); value != "" {
+		trackCacheTTL, err = time.ParseDuration(value)
+		if err != nil {
+			return Config{}, errors.Wrap(err, "TrackCacheTTL was invalid")
+		}
+	}
+
 	config := Config{
 		SpotifyPlaylistId:   spotifyPlaylistId,
 		PlaylistSize:        playlistSize,
 		SpotifyClientId:     spotifyClientId,
 		SpotifyClientSecret: spotifyClientSecret,
 		SpotifyRefreshToken: spotifyRefreshToken,
+		Station:             station,
+		RadioSource:         radioSource,
+		RadioSourceURL:      os.Getenv("RADIO_SOURCE_URL"),
+		RadioSourceFile:     os.Getenv("RADIO_SOURCE_FILE"),
+		RadioSourceFields:   sourceFields,
+		DedupPolicy:         strings.ToLower(os.Getenv("DEDUP_POLICY")),
+		MatchThreshold:      matchThreshold,
+		SkipExplicit:        skipExplicit,
+		SpotifyMarket:       strings.ToUpper(os.Getenv("SPOTIFY_MARKET")),
+		MusicBrainzURL:      os.Getenv("MUSICBRAINZ_URL"),
+		PlaylistOrder:       strings.ToLower(os.Getenv("PLAYLIST_ORDER")),
+		PlaylistDetails: spotify.PlaylistDetails{
+			Name:          playlistName,
+			Public:        &playlistPublic,
+			Collaborative: &playlistCollaborative,
+		},
+		PlaylistDescription: playlistDescription,
+		PlaylistTimezone:    playlistTimezone,
+		SpotifyTokenFile:    tokenFile(),
+		SpotifyTokenKey:     tokenKey,
+		TrackCache:          strings.ToLower(os.Getenv("TRACK_CACHE")),
+		TrackCacheFile:      os.Getenv("TRACK_CACHE_FILE"),
+		TrackCacheTTL:       trackCacheTTL,
+	}
+	if config.DedupPolicy == "" {
+		config.DedupPolicy = DedupKeepLatest
+	}
+	if config.PlaylistOrder == "" {
+		config.PlaylistOrder = OrderOldestFirst
+	}
+	if config.SpotifyMarket == "" {
+		config.SpotifyMarket = spotify.Market
+	}
+	if config.TrackCache == "" {
+		config.TrackCache = TrackCacheMemory
+	}
+	if config.TrackCacheFile == "" {
+		config.TrackCacheFile = DefaultTrackCacheFile
 	}
 
 	err = validateConfig(config)
@@ -45,8 +284,14 @@ func Load() (Config, error) {
 }
 
 func validateConfig(config Config) error {
+	// the details are only used to find or create the playlist when there is no playlist id
 	if len(config.SpotifyPlaylistId) == 0 {
-		return errors.New("empty SpotifyPlaylistId")
+		if len(config.PlaylistDetails.Name) == 0 {
+			return errors.New("empty SpotifyPlaylistId and PlaylistName")
+		}
+		if err := config.PlaylistDetails.Validate(); err != nil {
+			return errors.Wrap(err, "PlaylistDetails were invalid")
+		}
 	}
 	if config.PlaylistSize < 1 {
 		return errors.New("playlist size was smaller then 1")
@@ -54,11 +299,95 @@ func validateConfig(config Config) error {
 	if len(config.SpotifyClientId) == 0 {
 		return errors.New("empty SpotifyClientId")
 	}
-	if len(config.SpotifyClientSecret) == 0 {
+	// the secret isn't needed to refresh tokens from the auth command, which are saved to the token file
+	if len(config.SpotifyRefreshToken) > 0 && len(config.SpotifyClientSecret) == 0 {
 		return errors.New("empty SpotifyClientSecret")
 	}
-	if len(config.SpotifyRefreshToken) == 0 {
-		return errors.New("empty SpotifyRefreshToken")
+	if !marketPattern.MatchString(config.SpotifyMarket) {
+		return errors.Errorf("SpotifyMarket must be a two letter country code, got: %s", config.SpotifyMarket)
+	}
+	if config.MatchThreshold <= 0 || config.MatchThreshold > 1 {
+		return errors.New("match threshold must be above 0 and at most 1")
+	}
+	switch config.DedupPolicy {
+	case DedupKeepLatest, DedupKeepFirst, DedupAllowRepeats:
+	default:
+		return errors.Errorf("unknown DedupPolicy: %s", config.DedupPolicy)
+	}
+	switch config.PlaylistOrder {
+	case OrderOldestFirst, OrderNewestFirst:
+	default:
+		return errors.Errorf("unknown PlaylistOrder: %s", config.PlaylistOrder)
+	}
+	switch config.TrackCache {
+	case TrackCacheFile, TrackCacheMemory, TrackCacheOff:
+	default:
+		return errors.Errorf("unknown TrackCache: %s", config.TrackCache)
+	}
+	if config.TrackCacheTTL <= 0 {
+		return errors.New("track cache TTL must be above 0")
+	}
+	// other sources have no station to default to, and it names the playlist and its description
+	if config.RadioSource != RadioSourceABC && len(config.Station) == 0 {
+		return errors.New("empty RadioStation")
+	}
+	switch config.RadioSource {
+	case RadioSourceABC:
+	case RadioSourceJSON, RadioSourceICY:
+		if len(config.RadioSourceURL) == 0 {
+			return errors.New("empty RadioSourceURL")
+		}
+	case RadioSourceFile:
+		if len(config.RadioSourceFile) == 0 {
+			return errors.New("empty RadioSourceFile")
+		}
+	default:
+		return errors.Errorf("unknown RadioSource: %s", config.RadioSource)
 	}
 	return nil
 }
+
+// LoadAuth loads the config of the auth command, which only needs to know the spotify app.
+func LoadAuth() (AuthConfig, error) {
+	tokenKey, err := loadTokenKey()
+	if err != nil {
+		return AuthConfig{}, err
+	}
+
+	config := AuthConfig{
+		SpotifyClientId:    os.Getenv("SPOTIFY_CLIENT_ID"),
+		SpotifyRedirectURI: os.Getenv("SPOTIFY_REDIRECT_URI"),
+		SpotifyTokenFile:   tokenFile(),
+		SpotifyTokenKey:    tokenKey,
+	}
+	if config.SpotifyRedirectURI == "" {
+		config.SpotifyRedirectURI = spotify.DefaultRedirectURI
+	}
+	if len(config.SpotifyClientId) == 0 {
+		return AuthConfig{}, errors.New("empty SpotifyClientId")
+	}
+	return config, nil
+}
+
+func tokenFile() string {
+	if path := os.Getenv("SPOTIFY_TOKEN_FILE"); path != "" {
+		return path
+	}
+	return DefaultTokenFile
+}
+
+// loadTokenKey decodes SPOTIFY_TOKEN_KEY, the base64 encoded key the token file is encrypted with
+func loadTokenKey() ([]byte, error) {
+	value := os.Getenv("SPOTIFY_TOKEN_KEY")
+	if value == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "SpotifyTokenKey was invalid")
+	}
+	if len(key) != spotify.TokenKeySize {
+		return nil, errors.Errorf("SpotifyTokenKey must be %d bytes, got %d", spotify.TokenKeySize, len(key))
+	}
+	return key, nil
+}
@@ -8,7 +8,9 @@ import (
 
 	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/trackcache"
 )
 
 func RunBot() error {
@@ -40,10 +42,60 @@ func createBot(ctx context.Context, logger log.Log) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to load config")
 	}
-	bot := NewBot(cfg, logger)
+	tokenStore, err := newTokenStore(cfg.SpotifyTokenFile, cfg.SpotifyTokenKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to open token store")
+	}
+	if err := checkRefreshToken(ctx, cfg.SpotifyRefreshToken, tokenStore); err != nil {
+		return err
+	}
+
+	bot := NewBot(cfg, logger, spotify.WithTokenStore(tokenStore))
+	defer func() {
+		if err := bot.Close(); err != nil {
+			logger.RuntimeError(ctx, "failed to close track cache", err)
+		}
+	}()
 	err = bot.Run(ctx)
 	if err != nil {
 		return errors.Wrap(err, "bot ran into an error")
 	}
 	return nil
 }
+
+// checkRefreshToken makes sure there is a refresh token to get access tokens with, either
+// from the environment or saved by the auth command.
+func checkRefreshToken(ctx context.Context, refreshToken string, store spotify.TokenStore) error {
+	if refreshToken != "" {
+		return nil
+	}
+	token, err := store.Load(ctx)
+	if errors.Is(err, spotify.ErrNoToken) || (err == nil && token.RefreshToken == "") {
+		return errors.New("no SPOTIFY_REFRESH_TOKEN or saved token, run the auth command first")
+	}
+	return errors.Wrap(err, "failed to load saved token")
+}
+
+// newTokenStore returns the store spotify tokens are kept in, encrypted when there is a key
+func newTokenStore(path string, key []byte) (spotify.TokenStore, error) {
+	if key == nil {
+		return spotify.NewFileTokenStore(path), nil
+	}
+	return spotify.NewEncryptedFileTokenStore(path, key)
+}
+
+// newTrackCache returns the cache resolved songs are kept in, nil when caching is off
+func newTrackCache(cfg config.Config) (trackcache.Cache, error) {
+	switch cfg.TrackCache {
+	case config.TrackCacheFile:
+		cache, err := trackcache.OpenBoltCache(cfg.TrackCacheFile, trackcache.WithTTL(cfg.TrackCacheTTL))
+		if err != nil {
+			return nil, err
+		}
+		return cache, nil
+	case config.TrackCacheMemory:
+		return trackcache.NewMemoryCache(trackcache.WithTTL(cfg.TrackCacheTTL)), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+)
+
+func TestCheckRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	store := spotify.NewFileTokenStore(filepath.Join(t.TempDir(), "spotify-token.json"))
+
+	require.NoError(t, checkRefreshToken(ctx, "somerefreshtoken", store))
+	require.ErrorContains(t, checkRefreshToken(ctx, "", store), "run the auth command first")
+
+	require.NoError(t, store.Save(ctx, spotify.Token{AccessToken: "someaccesstoken"}))
+	require.ErrorContains(t, checkRefreshToken(ctx, "", store), "run the auth command first")
+
+	require.NoError(t, store.Save(ctx, spotify.Token{RefreshToken: "savedrefreshtoken"}))
+	require.NoError(t, checkRefreshToken(ctx, "", store))
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/normalise"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+// songKey identifies a recording regardless of when it was played. The ABC recording id is
+// used when there is one, otherwise the normalised title and artists.
+func songKey(song triplej.RadioSong) string {
+	if song.Id != "" {
+		return "arid:" + song.Id
+	}
+	return titleKey(song)
+}
+
+// titleKey identifies a song by its normalised title and artists.
+func titleKey(song triplej.RadioSong) string {
+	// artists may be credited together or separately, so compare them one by one in any order
+	var artists []string
+	for _, artist := range normalise.SplitAllArtists(song.Artists) {
+		artists = append(artists, normalise.Key(artist))
+	}
+	sort.Strings(artists)
+	return "song:" + normalise.Key(song.Name) + "|" + strings.Join(artists, ",")
+}
+
+// dedupPlays applies the dedup policy to plays ordered newest first. The same play listed
+// back to back by the radio source is always collapsed, while repeat plays of a recording
+// within the window are kept or dropped according to the policy.
+func dedupPlays(songs []triplej.RadioSong, policy string) []triplej.RadioSong {
+	var plays []triplej.RadioSong
+	for i, song := range songs {
+		if i > 0 && song.PlayKey() == songs[i-1].PlayKey() {
+			continue
+		}
+		plays = append(plays, song)
+	}
+
+	switch policy {
+	case config.DedupAllowRepeats:
+		return plays
+	case config.DedupKeepFirst:
+		// walk oldest to newest so the earliest play of each recording is the one kept
+		seen := make(map[string]bool, len(plays))
+		kept := make([]triplej.RadioSong, 0, len(plays))
+		for i := len(plays) - 1; i >= 0; i-- {
+			key := songKey(plays[i])
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append([]triplej.RadioSong{plays[i]}, kept...)
+		}
+		return kept
+	default:
+		seen := make(map[string]bool, len(plays))
+		kept := make([]triplej.RadioSong, 0, len(plays))
+		for _, song := range plays {
+			key := songKey(song)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, song)
+		}
+		return kept
+	}
+}
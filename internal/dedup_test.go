@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+func TestSongKey(t *testing.T) {
+	require.Equal(t, "arid:mt8dZ1N3Qa", songKey(triplej.RadioSong{Id: "mt8dZ1N3Qa", Name: "Tongue Tied"}))
+
+	// without an arid, trivially different spellings share a key
+	require.Equal(t,
+		songKey(triplej.RadioSong{Name: "Good Luck, Babe!", Artists: []string{"Chappell Roan"}}),
+		songKey(triplej.RadioSong{Name: "good luck babe", Artists: []string{"CHAPPELL ROAN"}}),
+	)
+	require.Equal(t,
+		songKey(triplej.RadioSong{Name: "Tongue Tied", Artists: []string{"Marshmello", "YUNGBLUD"}}),
+		songKey(triplej.RadioSong{Name: "Tongue Tied", Artists: []string{"YUNGBLUD", "Marshmello"}}),
+	)
+}
+
+func TestDedupPlays(t *testing.T) {
+	// plays are newest first, with song "a" replayed and one play listed twice by the API
+	songs := []triplej.RadioSong{
+		{Id: "a", PlayId: "play4", Name: "a"},
+		{Id: "c", PlayId: "play3", Name: "c"},
+		{Id: "c", PlayId: "play3", Name: "c"},
+		{Id: "b", PlayId: "play2", Name: "b"},
+		{Id: "a", PlayId: "play1", Name: "a"},
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		want   []string
+	}{
+		{name: "keep latest", policy: config.DedupKeepLatest, want: []string{"play4", "play3", "play2"}},
+		{name: "default keeps latest", policy: "", want: []string{"play4", "play3", "play2"}},
+		{name: "keep first", policy: config.DedupKeepFirst, want: []string{"play3", "play2", "play1"}},
+		{name: "allow repeats", policy: config.DedupAllowRepeats, want: []string{"play4", "play3", "play2", "play1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, song := range dedupPlays(songs, tt.policy) {
+				got = append(got, song.PlayId)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"slices"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+)
+
+// newestTrack returns the most recently added track of a playlist kept in the given order,
+// and false if the playlist is empty.
+func newestTrack(playlist []spotify.Track, order string) (spotify.Track, bool) {
+	if len(playlist) == 0 {
+		return spotify.Track{}, false
+	}
+	if order == config.OrderNewestFirst {
+		return playlist[0], true
+	}
+	return playlist[len(playlist)-1], true
+}
+
+// oldestTracks returns the n tracks added longest ago to a playlist kept in the given order.
+func oldestTracks(playlist []spotify.Track, n int, order string) []spotify.Track {
+	n = min(n, len(playlist))
+	if order == config.OrderNewestFirst {
+		return playlist[len(playlist)-n:]
+	}
+	return playlist[:n]
+}
+
+// inPlaylistOrder arranges songs ordered oldest first the way a playlist kept in the given order holds them.
+func inPlaylistOrder(songs []string, order string) []string {
+	if order != config.OrderNewestFirst {
+		return songs
+	}
+	songs = slices.Clone(songs)
+	slices.Reverse(songs)
+	return songs
+}
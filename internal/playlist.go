@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+)
+
+// descriptionData is what the playlist description template is executed with
+type descriptionData struct {
+	// Updated is when the run finished, in the configured timezone
+	Updated time.Time
+	// Songs is how many songs the playlist holds after the run
+	Songs int
+	// Station is the display name of the radio station
+	Station string
+}
+
+// readPrivateScope lets the bot see the user's private playlists
+const readPrivateScope = "playlist-read-private"
+
+// ensurePlaylist finds the playlist to fill when no playlist id is configured, creating it
+// if the user doesn't have one by that name yet.
+func (b *Bot) ensurePlaylist(ctx context.Context) error {
+	if b.spotifyPlaylistId != "" {
+		return nil
+	}
+
+	playlist, err := b.spotifyClient.FindPlaylist(ctx, b.playlistDetails.Name)
+	switch {
+	case err == nil:
+		b.log.InfoContext(ctx, "found playlist", "name", playlist.Name, "playlistId", playlist.Id)
+	case errors.Is(err, spotify.ErrPlaylistNotFound):
+		if err := b.checkCanFindPrivate(ctx); err != nil {
+			return err
+		}
+		playlist, err = b.spotifyClient.CreatePlaylist(ctx, b.playlistDetails)
+		if err != nil {
+			return errors.Wrap(err, "failed to create playlist")
+		}
+		b.log.InfoContext(ctx, "created playlist, set SPOTIFY_PLAYLIST_ID to use it directly", "name", playlist.Name, "playlistId", playlist.Id)
+	default:
+		return errors.Wrap(err, "failed to find playlist")
+	}
+	b.spotifyPlaylistId = playlist.Id
+	return nil
+}
+
+// checkCanFindPrivate makes sure a private playlist created now can be found on later runs.
+// Without the scope to read private playlists every run would create another one.
+func (b *Bot) checkCanFindPrivate(ctx context.Context) error {
+	if b.playlistDetails.Public == nil || *b.playlistDetails.Public {
+		return nil
+	}
+	scopes, err := b.spotifyClient.GrantedScopes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get granted scopes")
+	}
+	if !slices.Contains(scopes, readPrivateScope) {
+		return errors.Errorf("a private playlist can't be found again without the %s scope, so none was created: run the auth command again or set SPOTIFY_PLAYLIST_ID", readPrivateScope)
+	}
+	return nil
+}
+
+// describePlaylist rewrites the playlist description from the description template. The
+// description is only cosmetic, so failing to update it is logged rather than failing the run.
+func (b *Bot) describePlaylist(ctx context.Context, songs int) {
+	if b.description == nil {
+		return
+	}
+
+	var description strings.Builder
+	err := b.description.Execute(&description, descriptionData{
+		Updated: b.now().In(b.timezone),
+		Songs:   songs,
+		Station: b.station.DisplayName(),
+	})
+	if err != nil {
+		b.log.RuntimeError(ctx, "could not write playlist description", err)
+		return
+	}
+
+	err = b.spotifyClient.UpdatePlaylistDetails(ctx, b.spotifyPlaylistId, spotify.PlaylistDetails{Description: description.String()})
+	if err != nil {
+		b.log.RuntimeError(ctx, "could not update playlist description", err)
+	}
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/internal/config"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+	mock_spotify "github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify/mocks"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+func TestBot_ensurePlaylist(t *testing.T) {
+	ctx := context.Background()
+	details := spotify.PlaylistDetails{Name: "triple j recently played"}
+
+	t.Run("configured playlist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		b := &Bot{spotifyClient: mock_spotify.NewMockClienter(ctrl), spotifyPlaylistId: "1234", playlistDetails: details, log: log.NewLogger()}
+
+		require.NoError(t, b.ensurePlaylist(ctx))
+		require.Equal(t, "1234", b.spotifyPlaylistId)
+	})
+
+	t.Run("existing playlist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		b := &Bot{spotifyClient: mockSpotifyClient, playlistDetails: details, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().FindPlaylist(ctx, details.Name).Return(spotify.Playlist{Id: "found", Name: details.Name}, nil)
+
+		require.NoError(t, b.ensurePlaylist(ctx))
+		require.Equal(t, "found", b.spotifyPlaylistId)
+	})
+
+	t.Run("new playlist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		b := &Bot{spotifyClient: mockSpotifyClient, playlistDetails: details, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().FindPlaylist(ctx, details.Name).Return(spotify.Playlist{}, errors.Wrap(spotify.ErrPlaylistNotFound, "no playlist"))
+		mockSpotifyClient.EXPECT().CreatePlaylist(ctx, details).Return(spotify.Playlist{Id: "created", Name: details.Name}, nil)
+
+		require.NoError(t, b.ensurePlaylist(ctx))
+		require.Equal(t, "created", b.spotifyPlaylistId)
+	})
+
+	t.Run("private playlist without the scope to find it again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		public := false
+		private := spotify.PlaylistDetails{Name: details.Name, Public: &public}
+		b := &Bot{spotifyClient: mockSpotifyClient, playlistDetails: private, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().FindPlaylist(ctx, details.Name).Return(spotify.Playlist{}, errors.Wrap(spotify.ErrPlaylistNotFound, "no playlist"))
+		mockSpotifyClient.EXPECT().GrantedScopes(ctx).Return([]string{"playlist-modify-private"}, nil)
+
+		require.ErrorContains(t, b.ensurePlaylist(ctx), "SPOTIFY_PLAYLIST_ID")
+		require.Empty(t, b.spotifyPlaylistId)
+	})
+
+	t.Run("new private playlist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		public := false
+		private := spotify.PlaylistDetails{Name: details.Name, Public: &public}
+		b := &Bot{spotifyClient: mockSpotifyClient, playlistDetails: private, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().FindPlaylist(ctx, details.Name).Return(spotify.Playlist{}, errors.Wrap(spotify.ErrPlaylistNotFound, "no playlist"))
+		mockSpotifyClient.EXPECT().GrantedScopes(ctx).Return(spotify.PlaylistScopes, nil)
+		mockSpotifyClient.EXPECT().CreatePlaylist(ctx, private).Return(spotify.Playlist{Id: "created", Name: details.Name}, nil)
+
+		require.NoError(t, b.ensurePlaylist(ctx))
+		require.Equal(t, "created", b.spotifyPlaylistId)
+	})
+
+	t.Run("lookup fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+		b := &Bot{spotifyClient: mockSpotifyClient, playlistDetails: details, log: log.NewLogger()}
+
+		mockSpotifyClient.EXPECT().FindPlaylist(ctx, details.Name).Return(spotify.Playlist{}, errors.New("invalid status code: 403"))
+
+		require.Error(t, b.ensurePlaylist(ctx))
+	})
+}
+
+func TestBot_describePlaylist(t *testing.T) {
+	ctx := context.Background()
+	sydney, err := time.LoadLocation(config.DefaultPlaylistTimezone)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	mockSpotifyClient := mock_spotify.NewMockClienter(ctrl)
+	b := &Bot{
+		spotifyClient:     mockSpotifyClient,
+		spotifyPlaylistId: "1234",
+		description:       template.Must(template.New("description").Parse(config.DefaultPlaylistDescription)),
+		timezone:          sydney,
+		station:           triplej.TripleJ,
+		now:               func() time.Time { return time.Date(2024, time.June, 3, 4, 32, 0, 0, time.UTC) },
+		log:               log.NewLogger(),
+	}
+
+	mockSpotifyClient.EXPECT().UpdatePlaylistDetails(ctx, "1234", spotify.PlaylistDetails{Description: "Last updated 14:32 AEST — 30 songs from triple j"}).Return(nil)
+
+	b.describePlaylist(ctx, 30)
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+// errExplicit means a song was left out because it is explicit and has no clean version
+var errExplicit = errors.New("explicit and no clean version was found")
+
+// unresolvable reports whether err means the song can't be added to the playlist, rather
+// than that looking it up failed
+func unresolvable(err error) bool {
+	return errors.Is(err, errExplicit) || errors.Is(err, spotify.ErrNoMatch) || errors.Is(err, spotify.ErrUnplayable)
+}
+
+// runSummary collects what a run changed in the playlist, and which songs were left out and why
+type runSummary struct {
+	added   []string
+	removed []string
+	skipped []string
+}
+
+func (s *runSummary) skip(song triplej.RadioSong, reason error) {
+	s.skipped = append(s.skipped, strings.Join(song.Artists, ", ")+" - "+song.Name+": "+reason.Error())
+}
+
+func (s *runSummary) log(ctx context.Context, logger log.Log) {
+	logger.InfoContext(ctx, "run summary", "added", s.added, "removed", s.removed, "skipped", s.skipped)
+}
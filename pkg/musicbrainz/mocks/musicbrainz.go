@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: musicbrainz.go
+
+// Package mock_musicbrainz is a generated GoMock package.
+package mock_musicbrainz
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockClienter is a mock of Clienter interface.
+type MockClienter struct {
+	ctrl     *gomock.Controller
+	recorder *MockClienterMockRecorder
+}
+
+// MockClienterMockRecorder is the mock recorder for MockClienter.
+type MockClienterMockRecorder struct {
+	mock *MockClienter
+}
+
+// NewMockClienter creates a new mock instance.
+func NewMockClienter(ctrl *gomock.Controller) *MockClienter {
+	mock := &MockClienter{ctrl: ctrl}
+	mock.recorder = &MockClienterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClienter) EXPECT() *MockClienterMockRecorder {
+	return m.recorder
+}
+
+// LookupISRCs mocks base method.
+func (m *MockClienter) LookupISRCs(ctx context.Context, recordingId string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LookupISRCs", ctx, recordingId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LookupISRCs indicates an expected call of LookupISRCs.
+func (mr *MockClienterMockRecorder) LookupISRCs(ctx, recordingId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupISRCs", reflect.TypeOf((*MockClienter)(nil).LookupISRCs), ctx, recordingId)
+}
@@ -0,0 +1,119 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+)
+
+const (
+	DefaultBaseURL = "https://musicbrainz.org/ws/2"
+	defaultTimeout = 10 * time.Second
+	// MusicBrainz asks clients to identify themselves with a way to get in touch
+	defaultUserAgent = "triplej-playlist-generator ( https://github.com/JamesBLewis/triplej-playlist-generator )"
+)
+
+// Clienter looks up the ISRCs of a recording from its MusicBrainz recording ID (MBID)
+type Clienter interface {
+	LookupISRCs(ctx context.Context, recordingId string) ([]string, error)
+}
+
+//go:generate mockgen -destination=mocks/musicbrainz.go -source=musicbrainz.go
+
+// Client talks to the MusicBrainz web service, or any endpoint that serves the same
+// recording lookups.
+type Client struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// Option configures optional behaviour of a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at a different MusicBrainz compatible web service, e.g. a mirror.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the http.Client used to call the web service.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent to the web service.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+type recordingResponse struct {
+	Id    string   `json:"id"`
+	Isrcs []string `json:"isrcs"`
+}
+
+// NewClient returns a client for the public MusicBrainz web service unless WithBaseURL says otherwise.
+func NewClient(opts ...Option) Client {
+	c := Client{
+		baseURL:    DefaultBaseURL,
+		userAgent:  defaultUserAgent,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// LookupISRCs returns the ISRCs MusicBrainz holds for the recording. A recording can have
+// several, e.g. when it was released by different labels, or none at all.
+func (c Client) LookupISRCs(ctx context.Context, recordingId string) ([]string, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "LookupISRCs")
+	defer childSpan.End()
+
+	requestUrl, err := url.JoinPath(c.baseURL, "recording", recordingId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to construct request url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new request")
+	}
+
+	query := req.URL.Query()
+	query.Set("inc", "isrcs")
+	query.Set("fmt", "json")
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	recording := &recordingResponse{}
+	if err := json.NewDecoder(res.Body).Decode(recording); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	return recording.Isrcs, nil
+}
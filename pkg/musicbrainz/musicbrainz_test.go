@@ -0,0 +1,53 @@
+package musicbrainz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_LookupISRCs(t *testing.T) {
+	const recordingId = "0b5c7c3a-0d3c-4a1e-9a41-6f4a1d8e1c55"
+
+	t.Run("recording with isrcs", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/recording/"+recordingId, r.URL.Path)
+			require.Equal(t, "isrcs", r.URL.Query().Get("inc"))
+			require.Equal(t, "json", r.URL.Query().Get("fmt"))
+			require.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+			_, _ = w.Write([]byte(`{"id":"` + recordingId + `","title":"Tongue Tied","isrcs":["USUM72401234","GBUM72401234"]}`))
+		}))
+		defer server.Close()
+
+		c := NewClient(WithBaseURL(server.URL), WithUserAgent("test-agent"))
+		got, err := c.LookupISRCs(context.Background(), recordingId)
+		require.NoError(t, err)
+		require.Equal(t, []string{"USUM72401234", "GBUM72401234"}, got)
+	})
+
+	t.Run("recording without isrcs", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"id":"` + recordingId + `","title":"Tongue Tied","isrcs":[]}`))
+		}))
+		defer server.Close()
+
+		c := NewClient(WithBaseURL(server.URL))
+		got, err := c.LookupISRCs(context.Background(), recordingId)
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+
+	t.Run("unknown recording", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		c := NewClient(WithBaseURL(server.URL))
+		_, err := c.LookupISRCs(context.Background(), recordingId)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,118 @@
+package normalise
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// punctuation radio metadata uses that plain ASCII does just as well
+var punctuationReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "′", "'", "`", "'",
+	"“", `"`, "”", `"`, "„", `"`, "″", `"`,
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-",
+	"…", "...",
+	"\u00a0", " ",
+)
+
+// Fold strips diacritics and replaces typographic quotes, dashes and spaces with their plain
+// ASCII equivalents, so "Beyoncé – Halo" becomes "Beyonce - Halo". Case is kept.
+func Fold(s string) string {
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(stripMarks, s)
+	if err != nil {
+		folded = s
+	}
+	return punctuationReplacer.Replace(folded)
+}
+
+// Clean folds s and collapses runs of whitespace, leaving text that is safe to search for
+func Clean(s string) string {
+	return strings.Join(strings.Fields(Fold(s)), " ")
+}
+
+// Key folds and lower cases s and reduces punctuation to single spaces, so trivially different
+// spellings of a title or artist compare equal. Apostrophes are dropped rather than spaced,
+// so "Don't" becomes "dont".
+func Key(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(Fold(s)), "'", "")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// ContainsWords reports whether the key s contains the key words on word boundaries
+func ContainsWords(s, words string) bool {
+	return strings.Contains(" "+s+" ", " "+words+" ")
+}
+
+var (
+	// listSeparator matches the ways radio metadata always separates different artists
+	listSeparator = regexp.MustCompile(`(?i)\s*(?:,|;|/|\svs\.?\s)\s*`)
+	// featuringSeparator matches the credit of a featured artist
+	featuringSeparator = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+`)
+	// joinSeparator matches words that join artists, but which also appear in the names of
+	// single acts such as "Mumford & Sons" or "Florence + the Machine"
+	joinSeparator = regexp.MustCompile(`(?i)\s+(?:&|\+|x|with)\s+`)
+)
+
+// SplitArtists splits a credit such as "Dom Dolla feat. Daya & Kito" into its artists. Words
+// like "&" only split the featured artists or the last artist of a list, e.g. "Flume, Tove Lo
+// & Vera Blue", so a band such as "Mumford & Sons" stays one artist.
+func SplitArtists(credit string) []string {
+	credit = Clean(credit)
+	featured := ""
+	if loc := featuringSeparator.FindStringIndex(credit); loc != nil {
+		credit, featured = credit[:loc[0]], credit[loc[1]:]
+	}
+
+	names := listSeparator.Split(credit, -1)
+	if len(names) > 1 {
+		last := names[len(names)-1]
+		names = append(names[:len(names)-1], joinSeparator.Split(last, -1)...)
+	}
+	return append(trimArtists(names), splitFeatured(featured)...)
+}
+
+// splitFeatured splits the artists credited after a featuring marker, where every separator
+// joins different artists.
+func splitFeatured(credit string) []string {
+	var names []string
+	for _, part := range listSeparator.Split(Clean(credit), -1) {
+		for _, featured := range featuringSeparator.Split(part, -1) {
+			names = append(names, joinSeparator.Split(featured, -1)...)
+		}
+	}
+	return trimArtists(names)
+}
+
+func trimArtists(names []string) []string {
+	var artists []string
+	for _, artist := range names {
+		if artist = strings.TrimSpace(artist); artist != "" {
+			artists = append(artists, artist)
+		}
+	}
+	return artists
+}
+
+// SplitAllArtists splits every credit in credits, dropping repeats of the same artist
+func SplitAllArtists(credits []string) []string {
+	var (
+		artists []string
+		seen    = make(map[string]bool)
+	)
+	for _, credit := range credits {
+		for _, artist := range SplitArtists(credit) {
+			if key := Key(artist); !seen[key] {
+				seen[key] = true
+				artists = append(artists, artist)
+			}
+		}
+	}
+	return artists
+}
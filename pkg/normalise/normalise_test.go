@@ -0,0 +1,135 @@
+package normalise
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Beyoncé", want: "Beyonce"},
+		{in: "Sigur Rós", want: "Sigur Ros"},
+		{in: "Don’t Stop", want: "Don't Stop"},
+		{in: "“Heroes”", want: `"Heroes"`},
+		{in: "Crowded House – Weather With You", want: "Crowded House - Weather With You"},
+		{in: "Wait…", want: "Wait..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.want, Fold(tt.in))
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Don’t Stop Me Now!", want: "dont stop me now"},
+		{in: "  Mötley   Crüe ", want: "motley crue"},
+		{in: "AC/DC", want: "ac dc"},
+		{in: "P!nk", want: "p nk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.want, Key(tt.in))
+		})
+	}
+}
+
+func TestSplitArtists(t *testing.T) {
+	tests := []struct {
+		credit string
+		want   []string
+	}{
+		{credit: "Tame Impala", want: []string{"Tame Impala"}},
+		{credit: "Dom Dolla feat. Daya", want: []string{"Dom Dolla", "Daya"}},
+		{credit: "Fisher ft Kita Alexander", want: []string{"Fisher", "Kita Alexander"}},
+		{credit: "Dom Dolla feat. Daya & Kito", want: []string{"Dom Dolla", "Daya", "Kito"}},
+		{credit: "Fisher feat. Kita Alexander x Chris Lake", want: []string{"Fisher", "Kita Alexander", "Chris Lake"}},
+		{credit: "Flume, Tove Lo", want: []string{"Flume", "Tove Lo"}},
+		{credit: "Flume, Tove Lo & Vera Blue", want: []string{"Flume", "Tove Lo", "Vera Blue"}},
+		{credit: "Mumford & Sons", want: []string{"Mumford & Sons"}},
+		{credit: "Florence + the Machine", want: []string{"Florence + the Machine"}},
+		{credit: "Mumford & Sons feat. Baaba Maal", want: []string{"Mumford & Sons", "Baaba Maal"}},
+		{credit: "Mumford & Sons, Vera Blue", want: []string{"Mumford & Sons", "Vera Blue"}},
+		{credit: "Rüfüs Du Sol featuring Someone", want: []string{"Rufus Du Sol", "Someone"}},
+		{credit: "Xavier Rudd", want: []string{"Xavier Rudd"}},
+		{credit: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.credit, func(t *testing.T) {
+			require.Equal(t, tt.want, SplitArtists(tt.credit))
+		})
+	}
+}
+
+func TestSplitAllArtists(t *testing.T) {
+	got := SplitAllArtists([]string{"Dom Dolla feat. Daya", "daya", "Kito"})
+	require.Equal(t, []string{"Dom Dolla", "Daya", "Kito"}, got)
+}
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  Title
+	}{
+		{
+			title: "Bad Guy",
+			want:  Title{Name: "Bad Guy"},
+		},
+		{
+			title: "Say Something (triple j Like A Version)",
+			want:  Title{Name: "Say Something", Versions: []string{"like a version"}},
+		},
+		{
+			title: "Greatest View (Live) - Radio Edit",
+			want:  Title{Name: "Greatest View", Versions: []string{"live", "radio edit"}},
+		},
+		{
+			title: "Rushing Back (feat. Vera Blue)",
+			want:  Title{Name: "Rushing Back", Featuring: []string{"Vera Blue"}},
+		},
+		{
+			title: "Rushing Back feat. Vera Blue & Someone",
+			want:  Title{Name: "Rushing Back", Featuring: []string{"Vera Blue", "Someone"}},
+		},
+		{
+			title: "Never Tear Us Apart - 2011 Remastered",
+			want:  Title{Name: "Never Tear Us Apart", Versions: []string{"remaster"}},
+		},
+		{
+			title: "Reckoner [Extended Mix]",
+			want:  Title{Name: "Reckoner", Versions: []string{"extended", "mix"}},
+		},
+		{
+			title: "Chapter (Part 2) - Interlude",
+			want:  Title{Name: "Chapter (Part 2) - Interlude"},
+		},
+		{
+			title: "Don’t Look Back in Anger (Unplugged)",
+			want:  Title{Name: "Don't Look Back in Anger", Versions: []string{"unplugged"}},
+		},
+		{
+			title: "Unbalanced (brackets",
+			want:  Title{Name: "Unbalanced (brackets"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			require.Equal(t, tt.want, ParseTitle(tt.title))
+		})
+	}
+}
+
+func TestVersionMarkers(t *testing.T) {
+	require.Equal(t, []string{"radio edit"}, VersionMarkers("Radio Edit"))
+	require.Equal(t, []string{"live", "acoustic"}, VersionMarkers("Acoustic Live Session"))
+	require.Equal(t, []string{"remix"}, VersionMarkers("RMX"))
+	require.Nil(t, VersionMarkers("Deluxe"))
+}
@@ -0,0 +1,135 @@
+package normalise
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Title is a song title split into the name of the song and the tags stations and stores add to it
+type Title struct {
+	// Name is the cleaned title without version tags or featured artists
+	Name string
+	// Versions are the version markers found in the title, e.g. "live" or "radio edit"
+	Versions []string
+	// Featuring lists the artists credited in the title rather than in the artist field
+	Featuring []string
+}
+
+// versionMarkers are the words in a title that set a recording apart from the original.
+// Longer markers come first so "radio edit" isn't also counted as an "edit".
+var versionMarkers = []string{
+	"like a version", "radio edit", "live", "remix", "acoustic", "edit", "extended", "demo",
+	"remaster", "instrumental", "karaoke", "cover", "mix", "unplugged", "stripped", "mono",
+}
+
+// markerAliases are other spellings of a version marker
+var markerAliases = map[string]string{
+	"remastered": "remaster",
+	"rmx":        "remix",
+	"re mix":     "remix",
+}
+
+var (
+	// featuring matches a featured artist credit at the start of a bracket or suffix
+	featuring = regexp.MustCompile(`(?i)^(?:feat\.?|ft\.?|featuring|with)\s+`)
+	// inlineFeaturing matches a featured artist credit in the middle of a title
+	inlineFeaturing = regexp.MustCompile(`(?i)\s(?:feat\.?|ft\.?|featuring)\s`)
+)
+
+// ParseTitle separates the version markers and featured artists from a title, so
+// "Paper Planes (feat. Someone) [triple j Like A Version] - Radio Edit" becomes the name
+// "Paper Planes", the versions "like a version" and "radio edit", and the artist "Someone".
+// Brackets and suffixes that are neither are kept in the name.
+func ParseTitle(title string) Title {
+	var (
+		parsed Title
+		name   strings.Builder
+	)
+
+	// pull out the bracketed parts first
+	rest := Clean(title)
+	for {
+		start := strings.IndexAny(rest, "([{")
+		if start < 0 {
+			name.WriteString(rest)
+			break
+		}
+		end := closingBracket(rest, start)
+		if end < 0 {
+			name.WriteString(rest)
+			break
+		}
+		name.WriteString(rest[:start])
+		if inner := strings.TrimSpace(rest[start+1 : end]); !parsed.tag(inner) {
+			name.WriteString(rest[start : end+1])
+		}
+		rest = rest[end+1:]
+	}
+
+	// then anything tacked on after a dash
+	parts := strings.Split(name.String(), " - ")
+	name.Reset()
+	name.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if !parsed.tag(strings.TrimSpace(part)) {
+			name.WriteString(" - " + part)
+		}
+	}
+
+	// and finally credits left in the title itself
+	base, credit := name.String(), ""
+	if loc := inlineFeaturing.FindStringIndex(base); loc != nil {
+		base, credit = base[:loc[0]], base[loc[1]:]
+		parsed.Featuring = append(parsed.Featuring, splitFeatured(credit)...)
+	}
+
+	parsed.Name = strings.Join(strings.Fields(base), " ")
+	return parsed
+}
+
+// tag records the featured artists or version markers in part of a title, and reports whether
+// there were any so the part can be dropped from the name.
+func (t *Title) tag(part string) bool {
+	if loc := featuring.FindStringIndex(part); loc != nil {
+		t.Featuring = append(t.Featuring, splitFeatured(part[loc[1]:])...)
+		return true
+	}
+	markers := VersionMarkers(part)
+	t.Versions = append(t.Versions, markers...)
+	return len(markers) > 0
+}
+
+// VersionMarkers returns the version markers in s, e.g. "live" and "acoustic" in "Live Acoustic Session"
+func VersionMarkers(s string) []string {
+	key := " " + Key(s) + " "
+	for alias, marker := range markerAliases {
+		key = strings.ReplaceAll(key, " "+alias+" ", " "+marker+" ")
+	}
+
+	var markers []string
+	for _, marker := range versionMarkers {
+		if strings.Contains(key, " "+marker+" ") {
+			markers = append(markers, marker)
+			key = strings.ReplaceAll(key, " "+marker+" ", " ")
+		}
+	}
+	return markers
+}
+
+// closingBracket returns the index of the bracket closing the one at start, or -1
+func closingBracket(s string, start int) int {
+	open, depth := s[start], 0
+	closing := map[byte]byte{'(': ')', '[': ']', '{': '}'}[open]
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
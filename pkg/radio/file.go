@@ -0,0 +1,172 @@
+package radio
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+// artistSeparator splits multiple artists held in a single CSV column
+const artistSeparator = ";"
+
+// FileSource reads plays from a local CSV or JSONL file, chosen by the file extension.
+//
+// CSV files need a header row naming the columns id, title, artists, played_at and duration.
+// Only title and artists are required, and multiple artists are separated by a semicolon.
+// JSONL files hold one play per line using the same names as keys, with artists as an array.
+//
+// Plays are returned newest first by played_at. Files without play times are assumed to
+// be a log of plays, written oldest first.
+type FileSource struct {
+	path    string
+	station triplej.Station
+}
+
+// filePlay is a single play as written in a file
+type filePlay struct {
+	Id       string   `json:"id"`
+	Title    string   `json:"title"`
+	Artists  []string `json:"artists"`
+	PlayedAt string   `json:"played_at"`
+	// Duration is in seconds
+	Duration float64 `json:"duration"`
+}
+
+// NewFileSource returns a source reading the file at path. The station name is
+// copied onto every song returned.
+func NewFileSource(path string, station triplej.Station) *FileSource {
+	return &FileSource{path: path, station: station}
+}
+
+func (s *FileSource) RecentSongs(_ context.Context, limit int) ([]triplej.RadioSong, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open plays file")
+	}
+	defer file.Close()
+
+	var plays []filePlay
+	switch strings.ToLower(filepath.Ext(s.path)) {
+	case ".csv":
+		plays, err = readCSVPlays(file)
+	case ".jsonl", ".ndjson":
+		plays, err = readJSONLPlays(file)
+	default:
+		return nil, errors.Errorf("unsupported plays file type: %s", s.path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read plays from %s", s.path)
+	}
+
+	songs := make([]triplej.RadioSong, 0, len(plays))
+	for _, play := range plays {
+		if play.Title == "" || len(play.Artists) == 0 {
+			continue
+		}
+		song := triplej.RadioSong{
+			Id:       play.Id,
+			Name:     play.Title,
+			Artists:  play.Artists,
+			Station:  s.station,
+			Duration: time.Duration(play.Duration * float64(time.Second)),
+		}
+		if play.PlayedAt != "" {
+			song.PlayedTime, err = time.Parse(time.RFC3339, play.PlayedAt)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid play time %q", play.PlayedAt)
+			}
+		}
+		songs = append(songs, song)
+	}
+
+	if !sortNewestFirst(songs) {
+		for i, j := 0, len(songs)-1; i < j; i, j = i+1, j-1 {
+			songs[i], songs[j] = songs[j], songs[i]
+		}
+	}
+	return truncate(songs, limit), nil
+}
+
+func readCSVPlays(r io.Reader) ([]filePlay, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("missing title column")
+	}
+	if _, ok := columns["artists"]; !ok {
+		return nil, errors.New("missing artists column")
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var plays []filePlay
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		play := filePlay{
+			Id:       column(record, "id"),
+			Title:    column(record, "title"),
+			PlayedAt: column(record, "played_at"),
+		}
+		for _, artist := range strings.Split(column(record, "artists"), artistSeparator) {
+			if artist = strings.TrimSpace(artist); artist != "" {
+				play.Artists = append(play.Artists, artist)
+			}
+		}
+		if duration := column(record, "duration"); duration != "" {
+			play.Duration, err = strconv.ParseFloat(duration, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid duration %q", duration)
+			}
+		}
+		plays = append(plays, play)
+	}
+	return plays, nil
+}
+
+func readJSONLPlays(r io.Reader) ([]filePlay, error) {
+	var plays []filePlay
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var play filePlay
+		if err := json.Unmarshal(scanner.Bytes(), &play); err != nil {
+			return nil, errors.Wrapf(err, "invalid play on line %d", line)
+		}
+		plays = append(plays, play)
+	}
+	return plays, scanner.Err()
+}
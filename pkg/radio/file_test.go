@@ -0,0 +1,74 @@
+package radio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+func TestFileSource_RecentSongs(t *testing.T) {
+	testCtx := context.Background()
+
+	t.Run("csv sorted by play time", func(t *testing.T) {
+		s := NewFileSource(filepath.Join("testdata", "plays.csv"), "curated")
+
+		got, err := s.RecentSongs(testCtx, 10)
+		require.NoError(t, err)
+		// the play without a title is skipped
+		require.Equal(t, []triplej.RadioSong{
+			{
+				Id:         "2",
+				Name:       "Tongue Tied",
+				Artists:    []string{"Marshmello", "YUNGBLUD"},
+				Station:    "curated",
+				PlayedTime: time.Date(2024, 7, 25, 10, 34, 15, 0, time.UTC),
+				Duration:   218 * time.Second,
+			},
+			{
+				Id:         "1",
+				Name:       "Espresso",
+				Artists:    []string{"Sabrina Carpenter"},
+				Station:    "curated",
+				PlayedTime: time.Date(2024, 7, 25, 10, 27, 3, 0, time.UTC),
+				Duration:   175 * time.Second,
+			},
+		}, got)
+	})
+
+	t.Run("jsonl without play times is read as a log", func(t *testing.T) {
+		s := NewFileSource(filepath.Join("testdata", "plays.jsonl"), "curated")
+
+		got, err := s.RecentSongs(testCtx, 2)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, "Bad Dreams", got[0].Name)
+		require.Equal(t, "Tongue Tied", got[1].Name)
+	})
+
+	t.Run("malformed jsonl", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "plays.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte("{\"title\":\n"), 0o600))
+
+		_, err := NewFileSource(path, "curated").RecentSongs(testCtx, 10)
+		require.Error(t, err)
+	})
+
+	t.Run("csv missing required columns", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "plays.csv")
+		require.NoError(t, os.WriteFile(path, []byte("id,title\n1,Espresso\n"), 0o600))
+
+		_, err := NewFileSource(path, "curated").RecentSongs(testCtx, 10)
+		require.Error(t, err)
+	})
+
+	t.Run("unsupported file type", func(t *testing.T) {
+		_, err := NewFileSource("plays.txt", "curated").RecentSongs(testCtx, 10)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,183 @@
+package radio
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+const (
+	// icyMetaBlockUnit is the number of bytes each unit of the metadata length byte stands for
+	icyMetaBlockUnit = 16
+	// icyTitleSeparator splits the artist from the title in a StreamTitle
+	icyTitleSeparator = " - "
+	// icyNowPlayingTimeout bounds how long RecentSongs listens for the current title
+	icyNowPlayingTimeout = 30 * time.Second
+)
+
+// ICYSource reads the song currently playing from the ICY (Shoutcast/Icecast) metadata
+// embedded in an HTTP audio stream. Stream titles are expected to look like "Artist - Title".
+type ICYSource struct {
+	url        string
+	station    triplej.Station
+	httpClient *http.Client
+}
+
+// NewICYSource returns a source listening to the audio stream at url. The station name is
+// copied onto every song returned.
+func NewICYSource(url string, station triplej.Station) *ICYSource {
+	return &ICYSource{
+		url:     url,
+		station: station,
+		// the stream never ends, so only the wait for headers is bounded
+		httpClient: &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: 10 * time.Second}},
+	}
+}
+
+// RecentSongs listens to the stream until it announces a title and returns that song.
+// A stream only knows what is playing now, so at most one song is returned.
+func (s *ICYSource) RecentSongs(ctx context.Context, limit int) ([]triplej.RadioSong, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "ICYSource.RecentSongs")
+	defer childSpan.End()
+
+	if limit < 1 {
+		return []triplej.RadioSong{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, icyNowPlayingTimeout)
+	defer cancel()
+
+	songs, err := s.Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	song, ok := <-songs
+	if !ok {
+		return nil, errors.New("stream ended before announcing a song")
+	}
+	return []triplej.RadioSong{song}, nil
+}
+
+// Stream connects to the audio stream and sends a song each time the stream title changes.
+// The audio itself is discarded. The channel is closed when ctx is cancelled or the stream ends.
+func (s *ICYSource) Stream(ctx context.Context) (<-chan triplej.RadioSong, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new request")
+	}
+	// ask the server to interleave metadata with the audio
+	req.Header.Set("Icy-MetaData", "1")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	metaInt, err := strconv.Atoi(res.Header.Get("icy-metaint"))
+	if err != nil || metaInt <= 0 {
+		res.Body.Close()
+		return nil, errors.New("stream does not provide ICY metadata")
+	}
+
+	songs := make(chan triplej.RadioSong)
+	go func() {
+		defer close(songs)
+		defer res.Body.Close()
+
+		err := s.readMetadata(ctx, bufio.NewReader(res.Body), metaInt, songs)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Warning: reading ICY stream failed:", err)
+		}
+	}()
+	return songs, nil
+}
+
+// readMetadata skips metaInt bytes of audio before each metadata block, sending a song
+// whenever the block announces a new title.
+func (s *ICYSource) readMetadata(ctx context.Context, stream *bufio.Reader, metaInt int, songs chan<- triplej.RadioSong) error {
+	var lastTitle string
+	for {
+		if _, err := io.CopyN(io.Discard, stream, int64(metaInt)); err != nil {
+			return errors.Wrap(err, "failed to skip audio")
+		}
+
+		length, err := stream.ReadByte()
+		if err != nil {
+			return errors.Wrap(err, "failed to read metadata length")
+		}
+		// an empty block means the metadata hasn't changed
+		if length == 0 {
+			continue
+		}
+
+		block := make([]byte, int(length)*icyMetaBlockUnit)
+		if _, err := io.ReadFull(stream, block); err != nil {
+			return errors.Wrap(err, "failed to read metadata")
+		}
+
+		title := parseStreamTitle(string(block))
+		if title == "" || title == lastTitle {
+			continue
+		}
+		lastTitle = title
+
+		song, ok := s.songFromTitle(title)
+		if !ok {
+			continue
+		}
+		select {
+		case songs <- song:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *ICYSource) songFromTitle(title string) (triplej.RadioSong, bool) {
+	artist, name, ok := strings.Cut(title, icyTitleSeparator)
+	artist, name = strings.TrimSpace(artist), strings.TrimSpace(name)
+	// titles without an artist are usually station idents or ads
+	if !ok || artist == "" || name == "" {
+		return triplej.RadioSong{}, false
+	}
+	return triplej.RadioSong{
+		Name:       name,
+		Artists:    []string{artist},
+		Station:    s.station,
+		PlayedTime: time.Now(),
+	}, true
+}
+
+// parseStreamTitle extracts the StreamTitle from a metadata block, which is padded with NUL bytes:
+//
+//	StreamTitle='Artist - Title';StreamUrl='';
+func parseStreamTitle(metadata string) string {
+	metadata = strings.TrimRight(metadata, "\x00")
+	_, rest, ok := strings.Cut(metadata, "StreamTitle='")
+	if !ok {
+		return ""
+	}
+	// titles may contain quotes themselves, so look for the closing quote and semicolon
+	if end := strings.Index(rest, "';"); end >= 0 {
+		return rest[:end]
+	}
+	return strings.TrimSuffix(rest, "'")
+}
@@ -0,0 +1,112 @@
+package radio
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testMetaInt = 32
+
+// newICYServer stands in for an audio stream, sending a metadata block after every
+// testMetaInt bytes of fake audio. An empty title sends an empty block.
+func newICYServer(t *testing.T, titles ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Icy-MetaData") != "1" {
+			t.Errorf("Expected Icy-MetaData header to be requested")
+		}
+		w.Header().Set("icy-metaint", "32")
+		w.WriteHeader(http.StatusOK)
+
+		var stream bytes.Buffer
+		for _, title := range titles {
+			stream.Write(bytes.Repeat([]byte{0xFF}, testMetaInt))
+			stream.Write(icyMetadataBlock(title))
+		}
+		_, err := w.Write(stream.Bytes())
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func icyMetadataBlock(title string) []byte {
+	if title == "" {
+		return []byte{0}
+	}
+	metadata := []byte("StreamTitle='" + title + "';StreamUrl='';")
+	blocks := (len(metadata) + icyMetaBlockUnit - 1) / icyMetaBlockUnit
+	padded := make([]byte, blocks*icyMetaBlockUnit)
+	copy(padded, metadata)
+	return append([]byte{byte(blocks)}, padded...)
+}
+
+func TestICYSource_Stream(t *testing.T) {
+	server := newICYServer(t,
+		"Sabrina Carpenter - Espresso",
+		"",
+		"Sabrina Carpenter - Espresso",
+		"You're listening to the radio",
+		"Marshmello & YUNGBLUD - Tongue Tied",
+		"Guns N' Roses - Sweet Child O' Mine",
+	)
+
+	s := NewICYSource(server.URL, "local")
+	songs, err := s.Stream(context.Background())
+	require.NoError(t, err)
+
+	var got []string
+	for song := range songs {
+		require.Equal(t, "local", song.Station.String())
+		require.False(t, song.PlayedTime.IsZero())
+		got = append(got, song.Artists[0]+" / "+song.Name)
+	}
+	// repeated titles, empty blocks and idents without an artist are skipped
+	require.Equal(t, []string{
+		"Sabrina Carpenter / Espresso",
+		"Marshmello & YUNGBLUD / Tongue Tied",
+		"Guns N' Roses / Sweet Child O' Mine",
+	}, got)
+}
+
+func TestICYSource_RecentSongs(t *testing.T) {
+	t.Run("returns the song playing now", func(t *testing.T) {
+		server := newICYServer(t, "", "Teddy Swims - Bad Dreams", "Sabrina Carpenter - Espresso")
+
+		got, err := NewICYSource(server.URL, "local").RecentSongs(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, "Bad Dreams", got[0].Name)
+		require.Equal(t, []string{"Teddy Swims"}, got[0].Artists)
+	})
+
+	t.Run("stream without metadata", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		_, err := NewICYSource(server.URL, "local").RecentSongs(context.Background(), 10)
+		require.Error(t, err)
+	})
+
+	t.Run("stream ends without a title", func(t *testing.T) {
+		server := newICYServer(t, "", "")
+
+		_, err := NewICYSource(server.URL, "local").RecentSongs(context.Background(), 10)
+		require.Error(t, err)
+	})
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	require.Equal(t, "A - B", parseStreamTitle("StreamTitle='A - B';StreamUrl='';\x00\x00"))
+	require.Equal(t, "It's - Here", parseStreamTitle("StreamTitle='It's - Here';"))
+	require.Equal(t, "", parseStreamTitle("StreamUrl='http://example.com';"))
+}
@@ -0,0 +1,234 @@
+package radio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+// FieldMapping describes where the fields of a play live in a "recently played" JSON response.
+// Each field is a dot separated path such as "track.title". A path that passes through an
+// array collects a value from every element, e.g. "artists.name".
+type FieldMapping struct {
+	// Items is the path to the array of plays. Leave it empty when the response is the array.
+	Items    string
+	Id       string
+	Title    string
+	Artists  string
+	PlayedAt string
+	// TimeFormat is the layout of PlayedAt. It defaults to RFC 3339.
+	TimeFormat string
+	// Duration is the path to the length of the song in seconds.
+	Duration string
+}
+
+// ParseFieldMapping parses a mapping written as comma separated key=path pairs, e.g.
+// "items=data.plays,title=song.name,artists=song.artists.name,played_at=timestamp".
+func ParseFieldMapping(mapping string) (FieldMapping, error) {
+	var fm FieldMapping
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, path, ok := strings.Cut(pair, "=")
+		if !ok {
+			return FieldMapping{}, errors.Errorf("invalid field mapping %q, expected key=path", pair)
+		}
+		path = strings.TrimSpace(path)
+		switch strings.TrimSpace(key) {
+		case "items":
+			fm.Items = path
+		case "id":
+			fm.Id = path
+		case "title":
+			fm.Title = path
+		case "artists":
+			fm.Artists = path
+		case "played_at":
+			fm.PlayedAt = path
+		case "time_format":
+			fm.TimeFormat = path
+		case "duration":
+			fm.Duration = path
+		default:
+			return FieldMapping{}, errors.Errorf("unknown field mapping key %q", key)
+		}
+	}
+	if fm.Title == "" || fm.Artists == "" {
+		return FieldMapping{}, errors.New("field mapping must include title and artists")
+	}
+	return fm, nil
+}
+
+// JSONSource reads plays from any HTTP endpoint returning JSON, using a FieldMapping
+// to find each song. Plays are expected newest first unless PlayedAt is mapped.
+type JSONSource struct {
+	url        string
+	station    triplej.Station
+	mapping    FieldMapping
+	httpClient *http.Client
+}
+
+// NewJSONSource returns a source reading the endpoint at url. The station name is
+// copied onto every song returned.
+func NewJSONSource(url string, station triplej.Station, mapping FieldMapping) *JSONSource {
+	return &JSONSource{
+		url:     url,
+		station: station,
+		mapping: mapping,
+		// matches the timeout of the other clients
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *JSONSource) RecentSongs(ctx context.Context, limit int) ([]triplej.RadioSong, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "JSONSource.RecentSongs")
+	defer childSpan.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new request")
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	var body any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+
+	items, ok := lookupPath(body, s.mapping.Items).([]any)
+	if !ok {
+		return nil, errors.Errorf("no array of plays found at %q", s.mapping.Items)
+	}
+
+	songs := make([]triplej.RadioSong, 0, len(items))
+	for _, item := range items {
+		song, err := s.mapSong(item)
+		if err != nil {
+			return nil, err
+		}
+		if song.Name == "" || len(song.Artists) == 0 {
+			continue
+		}
+		songs = append(songs, song)
+	}
+
+	if s.mapping.PlayedAt != "" {
+		sortNewestFirst(songs)
+	}
+	return truncate(songs, limit), nil
+}
+
+func (s *JSONSource) mapSong(item any) (triplej.RadioSong, error) {
+	song := triplej.RadioSong{
+		Id:      firstString(lookupPath(item, s.mapping.Id)),
+		Name:    firstString(lookupPath(item, s.mapping.Title)),
+		Artists: allStrings(lookupPath(item, s.mapping.Artists)),
+		Station: s.station,
+	}
+
+	if s.mapping.PlayedAt != "" {
+		if playedAt := firstString(lookupPath(item, s.mapping.PlayedAt)); playedAt != "" {
+			layout := s.mapping.TimeFormat
+			if layout == "" {
+				layout = time.RFC3339
+			}
+			playedTime, err := time.Parse(layout, playedAt)
+			if err != nil {
+				return triplej.RadioSong{}, errors.Wrapf(err, "invalid play time %q", playedAt)
+			}
+			song.PlayedTime = playedTime
+		}
+	}
+
+	if s.mapping.Duration != "" {
+		if seconds, err := strconv.ParseFloat(firstString(lookupPath(item, s.mapping.Duration)), 64); err == nil {
+			song.Duration = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	return song, nil
+}
+
+// lookupPath walks a dot separated path through decoded JSON. Arrays met along the way
+// are mapped over, so the result is an array of every matching value.
+func lookupPath(value any, path string) any {
+	if path == "" {
+		return value
+	}
+	key, rest, _ := strings.Cut(path, ".")
+	switch v := value.(type) {
+	case map[string]any:
+		return lookupPath(v[key], rest)
+	case []any:
+		var values []any
+		for _, element := range v {
+			found := lookupPath(element, path)
+			if nested, ok := found.([]any); ok {
+				values = append(values, nested...)
+			} else if found != nil {
+				values = append(values, found)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// allStrings flattens a looked up value into its non-empty string values.
+func allStrings(value any) []string {
+	var values []string
+	switch v := value.(type) {
+	case []any:
+		for _, element := range v {
+			values = append(values, allStrings(element)...)
+		}
+	case nil:
+	default:
+		if s := toString(v); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func firstString(value any) string {
+	values := allStrings(value)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,116 @@
+package radio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+func TestParseFieldMapping(t *testing.T) {
+	t.Run("valid mapping", func(t *testing.T) {
+		got, err := ParseFieldMapping("items=data.plays, title=song.name,artists=song.artists.name,played_at=timestamp,duration=song.length")
+		require.NoError(t, err)
+		require.Equal(t, FieldMapping{
+			Items:    "data.plays",
+			Title:    "song.name",
+			Artists:  "song.artists.name",
+			PlayedAt: "timestamp",
+			Duration: "song.length",
+		}, got)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := ParseFieldMapping("title=name,artists=artist,album=album")
+		require.Error(t, err)
+	})
+
+	t.Run("missing title", func(t *testing.T) {
+		_, err := ParseFieldMapping("artists=artist")
+		require.Error(t, err)
+	})
+}
+
+func TestJSONSource_RecentSongs(t *testing.T) {
+	testCtx := context.Background()
+
+	t.Run("nested plays", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`{"data":{"plays":[
+				{"id":7,"timestamp":"2024-07-25T10:27:03Z","song":{"name":"Espresso","length":175,"artists":[{"name":"Sabrina Carpenter"}]}},
+				{"id":8,"timestamp":"2024-07-25T10:34:15Z","song":{"name":"Tongue Tied","length":218,"artists":[{"name":"Marshmello"},{"name":"YUNGBLUD"}]}},
+				{"id":9,"timestamp":"2024-07-25T10:30:00Z","song":{"name":"Station ID","artists":[]}}
+			]}}`))
+			if err != nil {
+				t.Error(err)
+			}
+		}))
+		defer server.Close()
+
+		s := NewJSONSource(server.URL, "kexp", FieldMapping{
+			Items:    "data.plays",
+			Id:       "id",
+			Title:    "song.name",
+			Artists:  "song.artists.name",
+			PlayedAt: "timestamp",
+			Duration: "song.length",
+		})
+
+		got, err := s.RecentSongs(testCtx, 10)
+		require.NoError(t, err)
+		// the play without artists is skipped and the rest are sorted newest first
+		require.Equal(t, []triplej.RadioSong{
+			{
+				Id:         "8",
+				Name:       "Tongue Tied",
+				Artists:    []string{"Marshmello", "YUNGBLUD"},
+				Station:    "kexp",
+				PlayedTime: time.Date(2024, 7, 25, 10, 34, 15, 0, time.UTC),
+				Duration:   218 * time.Second,
+			},
+			{
+				Id:         "7",
+				Name:       "Espresso",
+				Artists:    []string{"Sabrina Carpenter"},
+				Station:    "kexp",
+				PlayedTime: time.Date(2024, 7, 25, 10, 27, 3, 0, time.UTC),
+				Duration:   175 * time.Second,
+			},
+		}, got)
+	})
+
+	t.Run("top level array is kept in order", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`[{"title":"newest","artist":"a"},{"title":"older","artist":"b"},{"title":"oldest","artist":"c"}]`))
+			if err != nil {
+				t.Error(err)
+			}
+		}))
+		defer server.Close()
+
+		s := NewJSONSource(server.URL, "curated", FieldMapping{Title: "title", Artists: "artist"})
+
+		got, err := s.RecentSongs(testCtx, 2)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, "newest", got[0].Name)
+		require.Equal(t, []string{"b"}, got[1].Artists)
+	})
+
+	t.Run("items path is not an array", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"data":{"plays":"none"}}`))
+		}))
+		defer server.Close()
+
+		s := NewJSONSource(server.URL, "curated", FieldMapping{Items: "data.plays", Title: "title", Artists: "artist"})
+
+		_, err := s.RecentSongs(testCtx, 10)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: radio.go
+
+// Package mock_radio is a generated GoMock package.
+package mock_radio
+
+import (
+	context "context"
+	reflect "reflect"
+
+	triplej "github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSource is a mock of Source interface.
+type MockSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockSourceMockRecorder
+}
+
+// MockSourceMockRecorder is the mock recorder for MockSource.
+type MockSourceMockRecorder struct {
+	mock *MockSource
+}
+
+// NewMockSource creates a new mock instance.
+func NewMockSource(ctrl *gomock.Controller) *MockSource {
+	mock := &MockSource{ctrl: ctrl}
+	mock.recorder = &MockSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSource) EXPECT() *MockSourceMockRecorder {
+	return m.recorder
+}
+
+// RecentSongs mocks base method.
+func (m *MockSource) RecentSongs(ctx context.Context, limit int) ([]triplej.RadioSong, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecentSongs", ctx, limit)
+	ret0, _ := ret[0].([]triplej.RadioSong)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecentSongs indicates an expected call of RecentSongs.
+func (mr *MockSourceMockRecorder) RecentSongs(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecentSongs", reflect.TypeOf((*MockSource)(nil).RecentSongs), ctx, limit)
+}
@@ -0,0 +1,41 @@
+package radio
+
+import (
+	"context"
+	"sort"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
+)
+
+// Source is anything that can report the songs a station has recently played.
+// Songs are returned newest first.
+type Source interface {
+	RecentSongs(ctx context.Context, limit int) ([]triplej.RadioSong, error)
+}
+
+//go:generate mockgen -destination=mocks/radio.go -source=radio.go
+
+// the ABC client is the original source of plays
+var _ Source = triplej.Client{}
+
+// sortNewestFirst orders songs by when they were played. It reports false and leaves
+// the songs untouched when any of them has no play time to sort by.
+func sortNewestFirst(songs []triplej.RadioSong) bool {
+	for _, song := range songs {
+		if song.PlayedTime.IsZero() {
+			return false
+		}
+	}
+	sort.SliceStable(songs, func(i, j int) bool {
+		return songs[i].PlayedTime.After(songs[j].PlayedTime)
+	})
+	return true
+}
+
+// truncate limits songs to at most limit entries
+func truncate(songs []triplej.RadioSong, limit int) []triplej.RadioSong {
+	if limit >= 0 && len(songs) > limit {
+		return songs[:limit]
+	}
+	return songs
+}
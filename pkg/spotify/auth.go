@@ -0,0 +1,249 @@
+package spotify
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+)
+
+const (
+	// AccountsURL is where spotify users authorise the bot and tokens are issued
+	AccountsURL = "https://accounts.spotify.com"
+	// DefaultRedirectURI must be added to the app's redirect URIs on the developer dashboard
+	DefaultRedirectURI = "http://127.0.0.1:8888/callback"
+)
+
+// PlaylistScopes are the scopes the bot needs to find, create and edit its playlist
+var PlaylistScopes = []string{"playlist-read-private", "playlist-modify-public", "playlist-modify-private"}
+
+type (
+	// PKCE is a proof key for code exchange, which lets the authorization code be exchanged
+	// without the client secret.
+	PKCE struct {
+		Verifier  string
+		Challenge string
+	}
+
+	// Authorizer runs spotify's authorization code flow with PKCE to get a refresh token
+	Authorizer struct {
+		clientId    string
+		redirectURI string
+		accountsURL string
+		scopes      []string
+		httpClient  *http.Client
+	}
+
+	// AuthOption configures optional settings on an Authorizer
+	AuthOption func(*Authorizer)
+
+	authTokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+)
+
+// WithAccountsURL points the authorizer at a different accounts service, e.g. a test server.
+func WithAccountsURL(accountsURL string) AuthOption {
+	return func(a *Authorizer) {
+		a.accountsURL = accountsURL
+	}
+}
+
+// WithScopes replaces the scopes asked for, which default to PlaylistScopes.
+func WithScopes(scopes ...string) AuthOption {
+	return func(a *Authorizer) {
+		a.scopes = scopes
+	}
+}
+
+// WithAuthHTTPClient sets the http.Client used to exchange the authorization code.
+func WithAuthHTTPClient(httpClient *http.Client) AuthOption {
+	return func(a *Authorizer) {
+		a.httpClient = httpClient
+	}
+}
+
+// NewAuthorizer returns an authorizer for the app with the given client id. The redirect URI
+// has to be an http URI on this machine, e.g. DefaultRedirectURI.
+func NewAuthorizer(clientId, redirectURI string, opts ...AuthOption) *Authorizer {
+	a := &Authorizer{
+		clientId:    clientId,
+		redirectURI: redirectURI,
+		accountsURL: AccountsURL,
+		scopes:      PlaylistScopes,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// NewPKCE returns a random code verifier with its S256 challenge
+func NewPKCE() (PKCE, error) {
+	verifier, err := randomString(64)
+	if err != nil {
+		return PKCE{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	return PKCE{Verifier: verifier, Challenge: base64.RawURLEncoding.EncodeToString(challenge[:])}, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to read random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthorizeURL is the page the user approves the bot on, which then redirects to redirectURI.
+func (a *Authorizer) AuthorizeURL(redirectURI, state string, pkce PKCE) string {
+	query := url.Values{}
+	query.Set("client_id", a.clientId)
+	query.Set("response_type", "code")
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("scope", strings.Join(a.scopes, " "))
+	query.Set("code_challenge_method", "S256")
+	query.Set("code_challenge", pkce.Challenge)
+	return a.accountsURL + "/authorize?" + query.Encode()
+}
+
+// Authorize listens on the redirect URI, calls open with the authorize URL for the user to
+// approve and exchanges the code spotify redirects back with for a token. A redirect URI
+// with port 0 listens on any free port.
+func (a *Authorizer) Authorize(ctx context.Context, open func(authorizeURL string) error) (Token, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "Authorize")
+	defer childSpan.End()
+
+	redirect, err := url.Parse(a.redirectURI)
+	if err != nil {
+		return Token{}, errors.Wrap(err, "invalid redirect uri")
+	}
+	if redirect.Scheme != "http" {
+		return Token{}, errors.Errorf("redirect uri must be http on this machine, got: %s", a.redirectURI)
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return Token{}, errors.Wrap(err, "failed to listen on redirect uri")
+	}
+	defer listener.Close()
+	redirect.Host = listener.Addr().String()
+	redirectURI := redirect.String()
+
+	pkce, err := NewPKCE()
+	if err != nil {
+		return Token{}, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return Token{}, err
+	}
+
+	codes := make(chan string, 1)
+	failures := make(chan error, 1)
+	// a redirect uri without a path is redirected back to the root
+	callbackPath := redirect.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path != callbackPath:
+			http.NotFound(w, r)
+			return
+		case query.Get("state") != state:
+			// not our redirect, so keep waiting for it
+			http.Error(w, "unexpected state", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			http.Error(w, "authorization failed: "+query.Get("error"), http.StatusBadRequest)
+			select {
+			case failures <- errors.Errorf("authorization failed: %s", query.Get("error")):
+			default:
+			}
+		default:
+			_, _ = fmt.Fprintln(w, "Authorized, you can close this window.")
+			select {
+			case codes <- query.Get("code"):
+			default:
+			}
+		}
+	})
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	if err := open(a.AuthorizeURL(redirectURI, state, pkce)); err != nil {
+		return Token{}, errors.Wrap(err, "failed to open authorize url")
+	}
+
+	select {
+	case code := <-codes:
+		return a.Exchange(ctx, code, redirectURI, pkce)
+	case err := <-failures:
+		return Token{}, err
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+}
+
+// Exchange swaps an authorization code for a token, proving with pkce that this is the
+// client that asked for the code.
+func (a *Authorizer) Exchange(ctx context.Context, code, redirectURI string, pkce PKCE) (Token, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", redirectURI)
+	data.Set("client_id", a.clientId)
+	data.Set("code_verifier", pkce.Verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.accountsURL+"/api/token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return Token{}, errors.Wrap(err, "failed to create new request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return Token{}, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Token{}, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	tokenResponse := &authTokenResponse{}
+	if err := json.NewDecoder(res.Body).Decode(tokenResponse); err != nil {
+		return Token{}, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	if tokenResponse.RefreshToken == "" {
+		return Token{}, errors.New("no refresh token in response")
+	}
+	return Token{
+		AccessToken:  tokenResponse.AccessToken,
+		RefreshToken: tokenResponse.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second),
+		Scope:        tokenResponse.Scope,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package spotify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newAccountsServer stands in for spotify's accounts service. Its authorize page approves
+// straight away, or refuses with denyWith, and its token endpoint checks the PKCE verifier.
+func newAccountsServer(t *testing.T, denyWith string) *httptest.Server {
+	t.Helper()
+	var challenge, redirectURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/authorize":
+			query := r.URL.Query()
+			require.Equal(t, "someclientid", query.Get("client_id"))
+			require.Equal(t, "code", query.Get("response_type"))
+			require.Equal(t, "S256", query.Get("code_challenge_method"))
+			require.Equal(t, "playlist-read-private playlist-modify-public playlist-modify-private", query.Get("scope"))
+			challenge, redirectURI = query.Get("code_challenge"), query.Get("redirect_uri")
+
+			callback := url.Values{"state": {query.Get("state")}}
+			if denyWith != "" {
+				callback.Set("error", denyWith)
+			} else {
+				callback.Set("code", "somecode")
+			}
+			http.Redirect(w, r, redirectURI+"?"+callback.Encode(), http.StatusFound)
+		case "/api/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "authorization_code", r.Form.Get("grant_type"))
+			require.Equal(t, "somecode", r.Form.Get("code"))
+			require.Equal(t, redirectURI, r.Form.Get("redirect_uri"))
+			verified := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			require.Equal(t, challenge, base64.RawURLEncoding.EncodeToString(verified[:]))
+			_, _ = w.Write([]byte(`{"access_token":"someaccesstoken","refresh_token":"somerefreshtoken","expires_in":3600}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// approve visits the authorize url as the user's browser would
+func approve(authorizeURL string) error {
+	res, err := http.Get(authorizeURL)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("approved", func(t *testing.T) {
+		server := newAccountsServer(t, "")
+		a := NewAuthorizer("someclientid", "http://127.0.0.1:0/callback", WithAccountsURL(server.URL))
+
+		token, err := a.Authorize(ctx, approve)
+		require.NoError(t, err)
+		require.Equal(t, "somerefreshtoken", token.RefreshToken)
+		require.Equal(t, "someaccesstoken", token.AccessToken)
+		require.WithinDuration(t, time.Now().Add(time.Hour), token.Expiry, time.Minute)
+	})
+
+	t.Run("redirect without a path", func(t *testing.T) {
+		server := newAccountsServer(t, "")
+		a := NewAuthorizer("someclientid", "http://127.0.0.1:0", WithAccountsURL(server.URL))
+
+		token, err := a.Authorize(ctx, approve)
+		require.NoError(t, err)
+		require.Equal(t, "somerefreshtoken", token.RefreshToken)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		server := newAccountsServer(t, "access_denied")
+		a := NewAuthorizer("someclientid", "http://127.0.0.1:0/callback", WithAccountsURL(server.URL))
+
+		_, err := a.Authorize(ctx, approve)
+		require.EqualError(t, err, "authorization failed: access_denied")
+	})
+
+	t.Run("redirect must be local http", func(t *testing.T) {
+		a := NewAuthorizer("someclientid", "https://example.com/callback")
+
+		_, err := a.Authorize(ctx, approve)
+		require.Error(t, err)
+	})
+}
+
+func TestNewPKCE(t *testing.T) {
+	pkce, err := NewPKCE()
+	require.NoError(t, err)
+	// RFC 7636 verifiers are 43 to 128 characters
+	require.GreaterOrEqual(t, len(pkce.Verifier), 43)
+	require.LessOrEqual(t, len(pkce.Verifier), 128)
+
+	challenge := sha256.Sum256([]byte(pkce.Verifier))
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(challenge[:]), pkce.Challenge)
+}
+
+func TestClient_refreshAccessToken_PKCE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Empty(t, r.Header.Get("Authorization"))
+		require.Equal(t, "someclientid", r.Form.Get("client_id"))
+		require.Equal(t, "somerefreshtoken", r.Form.Get("refresh_token"))
+		_, _ = w.Write([]byte(`{"access_token":"token1","expires_in":3600}`))
+	}))
+	defer server.Close()
+	sc := &Client{accountAPI: server.URL, clientId: "someclientid", refreshToken: "somerefreshtoken", httpClient: http.DefaultClient}
+
+	require.NoError(t, sc.refreshAccessToken(context.Background()))
+	require.Equal(t, "token1", sc.accessToken)
+}
@@ -0,0 +1,39 @@
+package spotify
+
+import (
+	"fmt"
+)
+
+// maxItemsPerRequest is the most tracks spotify accepts in one playlist edit
+const maxItemsPerRequest = 100
+
+// ChunkError reports a bulk playlist edit that failed part way through. The chunks before
+// Chunk were applied, so Applied items are already in (or out of) the playlist.
+type ChunkError struct {
+	// Op is the edit that failed, "add" or "remove"
+	Op string
+	// Chunk is the index of the chunk that failed, counting from 0
+	Chunk int
+	// Chunks is how many chunks the edit was split into
+	Chunks int
+	// Applied is how many items were applied before the failure
+	Applied int
+	Err     error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("%s chunk %d of %d failed with %d items already applied: %v", e.Op, e.Chunk+1, e.Chunks, e.Applied, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// chunk splits items into consecutive slices of at most size items
+func chunk[T any](items []T, size int) [][]T {
+	var chunks [][]T
+	for start := 0; start < len(items); start += size {
+		chunks = append(chunks, items[start:min(start+size, len(items))])
+	}
+	return chunks
+}
@@ -0,0 +1,152 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type addRequest struct {
+	Uris     []string `json:"uris"`
+	Position *int     `json:"position"`
+}
+
+func trackUris(n int) []string {
+	uris := make([]string, n)
+	for i := range uris {
+		uris[i] = fmt.Sprintf("uri:%d", i)
+	}
+	return uris
+}
+
+func TestChunk(t *testing.T) {
+	require.Empty(t, chunk([]int{}, 2))
+	require.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, chunk([]int{1, 2, 3, 4, 5}, 2))
+	require.Equal(t, [][]int{{1, 2}}, chunk([]int{1, 2}, 2))
+}
+
+func TestClient_AddSongsToPlaylist_Chunks(t *testing.T) {
+	t.Run("appends in order", func(t *testing.T) {
+		var got []addRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req addRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			got = append(got, req)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		songs := trackUris(250)
+		require.NoError(t, sc.AddSongsToPlaylist(context.Background(), songs, "playlistId"))
+
+		require.Len(t, got, 3)
+		require.Equal(t, songs[:100], got[0].Uris)
+		require.Equal(t, songs[100:200], got[1].Uris)
+		require.Equal(t, songs[200:], got[2].Uris)
+		for _, req := range got {
+			require.Nil(t, req.Position)
+		}
+	})
+
+	t.Run("inserts each chunk after the last", func(t *testing.T) {
+		var positions []int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req addRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.NotNil(t, req.Position)
+			positions = append(positions, *req.Position)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		position := 5
+		require.NoError(t, sc.addChunks(context.Background(), trackUris(201), "playlistId", &position))
+		require.Equal(t, []int{5, 105, 205}, positions)
+	})
+
+	t.Run("reports the chunk that failed", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 2 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		err := sc.AddSongsToPlaylist(context.Background(), trackUris(250), "playlistId")
+
+		var chunkErr *ChunkError
+		require.True(t, errors.As(err, &chunkErr), "expected a ChunkError, got %v", err)
+		require.Equal(t, "add", chunkErr.Op)
+		require.Equal(t, 1, chunkErr.Chunk)
+		require.Equal(t, 3, chunkErr.Chunks)
+		require.Equal(t, 100, chunkErr.Applied)
+		require.Equal(t, 2, requests, "chunks after the failure should not be sent")
+	})
+}
+
+func TestClient_RemoveSongsFromPlaylist_Chunks(t *testing.T) {
+	const playlistId = "playlistId"
+
+	songs := make([]Track, 150)
+	for i := range songs {
+		songs[i] = Track{Uri: fmt.Sprintf("uri:%d", i), Position: i}
+	}
+
+	t.Run("removes from the end using the latest snapshot", func(t *testing.T) {
+		var got []removeRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req removeRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			got = append(got, req)
+			_, _ = fmt.Fprintf(w, `{"snapshot_id":"snapshot%d"}`, len(got)+1)
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+		sc.setSnapshot(playlistId, "snapshot1")
+
+		require.NoError(t, sc.RemoveSongsFromPlaylist(context.Background(), songs, playlistId))
+
+		require.Len(t, got, 2)
+		require.Len(t, got[0].Tracks, 100)
+		require.Equal(t, []int{149}, got[0].Tracks[0].Positions)
+		require.Equal(t, []int{50}, got[0].Tracks[99].Positions)
+		require.Equal(t, "snapshot1", got[0].SnapshotId)
+		require.Len(t, got[1].Tracks, 50)
+		require.Equal(t, []int{49}, got[1].Tracks[0].Positions)
+		require.Equal(t, "snapshot2", got[1].SnapshotId)
+	})
+
+	t.Run("reports the chunk that failed", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 2 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"snapshot_id":"snapshot2"}`))
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		err := sc.RemoveSongsFromPlaylist(context.Background(), songs, playlistId)
+
+		var chunkErr *ChunkError
+		require.True(t, errors.As(err, &chunkErr), "expected a ChunkError, got %v", err)
+		require.Equal(t, ChunkError{Op: "remove", Chunk: 1, Chunks: 2, Applied: 100, Err: chunkErr.Err}, *chunkErr)
+		require.EqualError(t, err, "remove chunk 2 of 2 failed with 100 items already applied: invalid status code: 403")
+	})
+}
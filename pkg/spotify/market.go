@@ -0,0 +1,47 @@
+package spotify
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrUnplayable means a track can't be played in the client's market and has no playable relink
+var ErrUnplayable = errors.New("track is not playable in the market")
+
+// LinkedTrack is the track spotify originally returned before relinking it to a version
+// that is playable in the requested market.
+type LinkedTrack struct {
+	Id  string `json:"id"`
+	Uri string `json:"uri"`
+}
+
+// WithMarket sets the country, as an ISO 3166-1 alpha-2 code, that tracks must be playable
+// in. Spotify relinks tracks to the version available there where it can.
+func WithMarket(market string) Option {
+	return func(sc *Client) {
+		sc.market = market
+	}
+}
+
+// marketCode returns the market requests are made for
+func (sc *Client) marketCode() string {
+	if sc.market == "" {
+		return Market
+	}
+	return sc.market
+}
+
+// Playable reports whether the track can be played in the market it was requested for.
+// Tracks requested without a market are assumed to be playable.
+func (t Track) Playable() bool {
+	return t.IsPlayable == nil || *t.IsPlayable
+}
+
+// playlistUri returns the uri the track is stored under in a playlist. Spotify swaps relinked
+// tracks for the playable version when reading a playlist for a market, but edits to the
+// playlist must still name the original.
+func (t Track) playlistUri() string {
+	if t.LinkedFrom != nil && t.LinkedFrom.Uri != "" {
+		return t.LinkedFrom.Uri
+	}
+	return t.Uri
+}
@@ -0,0 +1,96 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// newMarketServer serves body for every request made for the NZ market
+func newMarketServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fields") && r.URL.Query().Get("fields") == "snapshot_id" {
+			_, _ = w.Write([]byte(`{"snapshot_id":"snapshot1"}`))
+			return
+		}
+		require.Equal(t, "NZ", r.URL.Query().Get("market"))
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newMarketClient(server *httptest.Server) *Client {
+	sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+	WithMarket("NZ")(sc)
+	return sc
+}
+
+func TestClient_GetTrackById_Market(t *testing.T) {
+	t.Run("relinked track", func(t *testing.T) {
+		server := newMarketServer(t, `{"uri":"spotify:track:nz","name":"Song","is_playable":true,"linked_from":{"id":"au","uri":"spotify:track:au"}}`)
+
+		track, err := newMarketClient(server).GetTrackById(context.Background(), "au")
+		require.NoError(t, err)
+		require.Equal(t, "spotify:track:nz", track.Uri)
+	})
+
+	t.Run("unplayable track", func(t *testing.T) {
+		server := newMarketServer(t, `{"uri":"spotify:track:au","name":"Song","is_playable":false}`)
+
+		_, err := newMarketClient(server).GetTrackById(context.Background(), "au")
+		require.True(t, errors.Is(err, ErrUnplayable), "expected ErrUnplayable, got %v", err)
+	})
+}
+
+func TestClient_GetCurrentPlaylist_Relinked(t *testing.T) {
+	server := newMarketServer(t, `{"items":[
+		{"track":{"uri":"spotify:track:nz","name":"Relinked","is_playable":true,"linked_from":{"id":"au","uri":"spotify:track:au"}}},
+		{"track":{"uri":"spotify:track:other","name":"Not relinked","is_playable":true}}
+	]}`)
+
+	songs, err := newMarketClient(server).GetCurrentPlaylist(context.Background(), "playlistId")
+	require.NoError(t, err)
+	require.Len(t, songs, 2)
+	// the playlist holds the original, so that is what edits have to name
+	require.Equal(t, "spotify:track:au", songs[0].Uri)
+	require.Equal(t, "spotify:track:other", songs[1].Uri)
+}
+
+func TestClient_MatchTrack_Market(t *testing.T) {
+	t.Run("skips unplayable candidates", func(t *testing.T) {
+		server := newMarketServer(t, `{"tracks":{"items":[
+			{"uri":"uri:unplayable","name":"Dreams","artists":[{"name":"Fleetwood Mac"}],"is_playable":false},
+			{"uri":"uri:playable","name":"Dreams - 2004 Remaster","artists":[{"name":"Fleetwood Mac"}],"is_playable":true}
+		]}}`)
+
+		match, err := newMarketClient(server).MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}})
+		require.NoError(t, err)
+		require.Equal(t, "uri:playable", match.Track.Uri)
+	})
+
+	t.Run("nothing playable", func(t *testing.T) {
+		server := newMarketServer(t, `{"tracks":{"items":[
+			{"uri":"uri:unplayable","name":"Dreams","artists":[{"name":"Fleetwood Mac"}],"is_playable":false}
+		]}}`)
+
+		_, err := newMarketClient(server).MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}})
+		require.True(t, errors.Is(err, ErrUnplayable), "expected ErrUnplayable, got %v", err)
+	})
+
+	t.Run("isrc picks a playable release", func(t *testing.T) {
+		server := newMarketServer(t, `{"tracks":{"items":[
+			{"uri":"uri:unplayable","name":"Dreams","is_playable":false},
+			{"uri":"uri:playable","name":"Dreams","is_playable":true}
+		]}}`)
+
+		match, err := newMarketClient(server).MatchISRC(context.Background(), "USWB10400001")
+		require.NoError(t, err)
+		require.Equal(t, "uri:playable", match.Track.Uri)
+	})
+}
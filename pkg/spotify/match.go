@@ -0,0 +1,298 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/normalise"
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+)
+
+const (
+	// DefaultMatchThreshold is the lowest confidence a search result needs to be accepted
+	DefaultMatchThreshold = 0.6
+	// MatchMethodSearch means the track was found by searching for its title and artists
+	MatchMethodSearch = "search"
+	// MatchMethodISRC means the track was found by its ISRC
+	MatchMethodISRC = "isrc"
+	// searchCandidates is how many search results are scored
+	searchCandidates = 10
+	// isrcCandidates is how many releases of a recording are considered
+	isrcCandidates = 5
+)
+
+// how much each part of a candidate counts towards its confidence
+const (
+	titleWeight    = 0.45
+	artistWeight   = 0.35
+	versionWeight  = 0.2
+	durationWeight = 0.15
+)
+
+// ErrNoMatch means no candidate matched the song closely enough to be trusted
+var ErrNoMatch = errors.New("no track matched closely enough")
+
+// isrcPattern matches an ISRC without hyphens: country, registrant, year and designation code
+var isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}[0-9]{7}$`)
+
+type (
+	// TrackQuery describes a song to find on spotify
+	TrackQuery struct {
+		Name    string
+		Artists []string
+		// Duration is zero when the length of the song isn't known
+		Duration time.Duration
+		// CleanOnly rules out explicit tracks, e.g. to find the clean version of a song
+		CleanOnly bool
+	}
+
+	// Match is the track that best matched a TrackQuery
+	Match struct {
+		Track Track
+		// Confidence ranges from 0 for no resemblance to 1 for an exact match
+		Confidence float64
+		// Method is how the track was found, e.g. MatchMethodSearch
+		Method string
+	}
+)
+
+// WithMatchThreshold sets the lowest confidence MatchTrack accepts, between 0 and 1
+func WithMatchThreshold(threshold float64) Option {
+	return func(sc *Client) {
+		sc.matchThreshold = threshold
+	}
+}
+
+// MatchTrack searches for the song and scores each candidate on how closely its title,
+// artists, version and duration match. The best candidate is returned unless its confidence
+// is below the match threshold, in which case the error wraps ErrNoMatch.
+func (sc *Client) MatchTrack(ctx context.Context, query TrackQuery) (Match, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "MatchTrack")
+	defer childSpan.End()
+
+	candidates, err := sc.searchTracks(ctx, searchQuery(query), searchCandidates)
+	if err != nil {
+		return Match{}, err
+	}
+
+	best := Match{Method: MatchMethodSearch}
+	unplayable := 0
+	for _, candidate := range candidates {
+		if query.CleanOnly && candidate.Explicit {
+			continue
+		}
+		// tracks spotify could relink are already playable, so these have no version in the market
+		if !candidate.Playable() {
+			unplayable++
+			continue
+		}
+		// ties go to the earlier candidate, as spotify ranks results by relevance
+		if confidence := scoreCandidate(query, candidate); confidence > best.Confidence || best.Track.Uri == "" {
+			best.Track = candidate
+			best.Confidence = confidence
+		}
+	}
+
+	childSpan.SetAttributes(attribute.Float64("match.confidence", best.Confidence))
+	if best.Track.Uri == "" && unplayable > 0 {
+		return Match{}, errors.Wrapf(ErrUnplayable, "%s %s in %s", query.Name, strings.Join(query.Artists, ", "), sc.marketCode())
+	}
+	if best.Track.Uri == "" {
+		return Match{}, errors.Wrapf(ErrNoMatch, "could not find track: %s %s", query.Name, strings.Join(query.Artists, ", "))
+	}
+
+	threshold := sc.matchThreshold
+	if threshold <= 0 {
+		threshold = DefaultMatchThreshold
+	}
+	if best.Confidence < threshold {
+		return Match{}, errors.Wrapf(ErrNoMatch, "best match for %s %s was %s with confidence %.2f", query.Name, strings.Join(query.Artists, ", "), best.Track.Uri, best.Confidence)
+	}
+	return best, nil
+}
+
+// MatchISRC finds the track with the given ISRC. An ISRC identifies a single recording, so
+// the match is exact, though the same recording may appear on several releases.
+func (sc *Client) MatchISRC(ctx context.Context, isrc string) (Match, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "MatchISRC")
+	defer childSpan.End()
+
+	if !isrcPattern.MatchString(isrc) {
+		return Match{}, fmt.Errorf("invalid isrc: %q", isrc)
+	}
+
+	candidates, err := sc.searchTracks(ctx, "isrc:"+isrc, isrcCandidates)
+	if err != nil {
+		return Match{}, err
+	}
+	if len(candidates) == 0 {
+		return Match{}, errors.Wrapf(ErrNoMatch, "could not find track with isrc %s", isrc)
+	}
+	// the recording may be on several releases, not all of them available in the market
+	for _, candidate := range candidates {
+		if candidate.Playable() {
+			return Match{Track: candidate, Confidence: 1, Method: MatchMethodISRC}, nil
+		}
+	}
+	return Match{}, errors.Wrapf(ErrUnplayable, "isrc %s in %s", isrc, sc.marketCode())
+}
+
+func (sc *Client) searchTracks(ctx context.Context, q string, limit int) ([]Track, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sc.musicAPI+"/search", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new request")
+	}
+
+	params := req.URL.Query()
+	params.Add("q", q)
+	params.Add("type", "track")
+	params.Add("market", sc.marketCode())
+	params.Add("limit", strconv.Itoa(limit))
+	req.URL.RawQuery = params.Encode()
+
+	res, err := sc.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	searchTracksResponse := &SearchTracksResponse{}
+	if err := json.NewDecoder(res.Body).Decode(searchTracksResponse); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	return searchTracksResponse.Tracks.Items, nil
+}
+
+// scoreCandidate returns how confident we are that candidate is the song in query
+func scoreCandidate(query TrackQuery, candidate Track) float64 {
+	queryTitle := normalise.ParseTitle(query.Name)
+	candidateTitle := normalise.ParseTitle(candidate.Name)
+
+	// artists featured in the title are usually credited as artists on spotify
+	wantedArtists := normalise.SplitAllArtists(append(slices.Clone(query.Artists), queryTitle.Featuring...))
+	creditedArtists := candidate.ArtistNames()
+
+	score := titleWeight*similarity(normalise.Key(queryTitle.Name), normalise.Key(candidateTitle.Name)) +
+		artistWeight*artistOverlap(wantedArtists, creditedArtists) +
+		versionWeight*versionAgreement(queryTitle.Versions, candidateTitle.Versions)
+	total := titleWeight + artistWeight + versionWeight
+
+	if query.Duration > 0 && candidate.DurationMs > 0 {
+		score += durationWeight * durationAgreement(query.Duration, time.Duration(candidate.DurationMs)*time.Millisecond)
+		total += durationWeight
+	}
+	return score / total
+}
+
+// searchQuery builds the search text for a song from its cleaned up title and artists, as
+// spotify search copes badly with bracketed tags and featured artist credits.
+func searchQuery(query TrackQuery) string {
+	title := normalise.ParseTitle(query.Name)
+	terms := append([]string{title.Name}, title.Versions...)
+	terms = append(terms, normalise.SplitAllArtists(query.Artists)...)
+	return strings.Join(terms, " ")
+}
+
+// similarity compares two normalised strings by the character pairs they share, from 0 to 1
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	pairsA, pairsB := bigrams(a), bigrams(b)
+	if len(pairsA) == 0 || len(pairsB) == 0 {
+		return 0
+	}
+	shared := 0
+	for pair, count := range pairsA {
+		shared += min(count, pairsB[pair])
+	}
+	return 2 * float64(shared) / float64(countPairs(pairsA)+countPairs(pairsB))
+}
+
+func bigrams(s string) map[string]int {
+	pairs := make(map[string]int)
+	runes := []rune(strings.ReplaceAll(s, " ", ""))
+	for i := 0; i+1 < len(runes); i++ {
+		pairs[string(runes[i:i+2])]++
+	}
+	return pairs
+}
+
+func countPairs(pairs map[string]int) int {
+	total := 0
+	for _, count := range pairs {
+		total += count
+	}
+	return total
+}
+
+// artistOverlap returns the share of wanted artists credited on the candidate
+func artistOverlap(wanted, credited []string) float64 {
+	if len(wanted) == 0 {
+		return 0
+	}
+	found := 0
+	for _, artist := range wanted {
+		artist = normalise.Key(artist)
+		for _, candidate := range credited {
+			candidate = normalise.Key(candidate)
+			// tolerate credits that add or drop a word, e.g. "The Jungle Giants" and "Jungle Giants"
+			if artist == candidate || normalise.ContainsWords(artist, candidate) || normalise.ContainsWords(candidate, artist) {
+				found++
+				break
+			}
+		}
+	}
+	return float64(found) / float64(len(wanted))
+}
+
+// versionAgreement is 1 when both titles carry the same version markers, losing half for
+// each marker only one of them has.
+func versionAgreement(wanted, candidate []string) float64 {
+	mismatches := 0
+	for _, marker := range wanted {
+		if !slices.Contains(candidate, marker) {
+			mismatches++
+		}
+	}
+	for _, marker := range candidate {
+		if !slices.Contains(wanted, marker) {
+			mismatches++
+		}
+	}
+	return max(0, 1-0.5*float64(mismatches))
+}
+
+// durationAgreement is 1 when the lengths are within a few seconds, falling to 0 at 30 seconds apart
+func durationAgreement(wanted, candidate time.Duration) float64 {
+	diff := wanted - candidate
+	if diff < 0 {
+		diff = -diff
+	}
+	const tolerance, limit = 3 * time.Second, 30 * time.Second
+	switch {
+	case diff <= tolerance:
+		return 1
+	case diff >= limit:
+		return 0
+	default:
+		return 1 - float64(diff-tolerance)/float64(limit-tolerance)
+	}
+}
@@ -0,0 +1,173 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// newSearchServer serves body for every search and checks enough candidates are asked for
+func newSearchServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/search", r.URL.Path)
+		require.Equal(t, "10", r.URL.Query().Get("limit"))
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_MatchTrack(t *testing.T) {
+	t.Run("prefers the original over karaoke and remixes", func(t *testing.T) {
+		server := newSearchServer(t, `{"tracks":{"items":[
+			{"uri":"uri:karaoke","name":"Dreams (Karaoke Version)","artists":[{"name":"Sing Along Stars"}],"duration_ms":250000},
+			{"uri":"uri:remix","name":"Dreams - Remix","artists":[{"name":"Fleetwood Mac"}],"duration_ms":310000},
+			{"uri":"uri:original","name":"Dreams","artists":[{"name":"Fleetwood Mac"}],"duration_ms":257000}
+		]}}`)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		match, err := sc.MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}, Duration: 257 * time.Second})
+		require.NoError(t, err)
+		require.Equal(t, "uri:original", match.Track.Uri)
+		require.Equal(t, MatchMethodSearch, match.Method)
+		require.InDelta(t, 1, match.Confidence, 0.001)
+	})
+
+	t.Run("matches the version that was played", func(t *testing.T) {
+		server := newSearchServer(t, `{"tracks":{"items":[
+			{"uri":"uri:original","name":"Dreams","artists":[{"name":"Fleetwood Mac"}]},
+			{"uri":"uri:live","name":"Dreams (Live)","artists":[{"name":"Fleetwood Mac"}]}
+		]}}`)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		match, err := sc.MatchTrack(context.Background(), TrackQuery{Name: "Dreams [Live]", Artists: []string{"Fleetwood Mac"}})
+		require.NoError(t, err)
+		require.Equal(t, "uri:live", match.Track.Uri)
+	})
+
+	t.Run("clean only skips explicit tracks", func(t *testing.T) {
+		server := newSearchServer(t, `{"tracks":{"items":[
+			{"uri":"uri:explicit","name":"Dreams","artists":[{"name":"Fleetwood Mac"}],"explicit":true},
+			{"uri":"uri:clean","name":"Dreams - Radio Edit","artists":[{"name":"Fleetwood Mac"}],"explicit":false}
+		]}}`)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		match, err := sc.MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}})
+		require.NoError(t, err)
+		require.Equal(t, "uri:explicit", match.Track.Uri)
+
+		match, err = sc.MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}, CleanOnly: true})
+		require.NoError(t, err)
+		require.Equal(t, "uri:clean", match.Track.Uri)
+		require.False(t, match.Track.Explicit)
+	})
+
+	t.Run("refuses matches below the threshold", func(t *testing.T) {
+		server := newSearchServer(t, `{"tracks":{"items":[
+			{"uri":"uri:cover","name":"Dreams - Acoustic Cover","artists":[{"name":"Some Busker"}]}
+		]}}`)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, matchThreshold: 0.8}
+
+		_, err := sc.MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}})
+		require.True(t, errors.Is(err, ErrNoMatch), "expected ErrNoMatch, got %v", err)
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		server := newSearchServer(t, `{"tracks":{"items":[]}}`)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		_, err := sc.MatchTrack(context.Background(), TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}})
+		require.True(t, errors.Is(err, ErrNoMatch), "expected ErrNoMatch, got %v", err)
+	})
+}
+
+func TestClient_MatchISRC(t *testing.T) {
+	t.Run("finds the track", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/search", r.URL.Path)
+			require.Equal(t, "isrc:USUM72401234", r.URL.Query().Get("q"))
+			require.Equal(t, "5", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"tracks":{"items":[{"uri":"uri:tongueTied","name":"Tongue Tied"}]}}`))
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		match, err := sc.MatchISRC(context.Background(), "USUM72401234")
+		require.NoError(t, err)
+		require.Equal(t, Match{Track: Track{Uri: "uri:tongueTied", Name: "Tongue Tied"}, Confidence: 1, Method: MatchMethodISRC}, match)
+	})
+
+	t.Run("unknown isrc", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"tracks":{"items":[]}}`))
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		_, err := sc.MatchISRC(context.Background(), "USUM72401234")
+		require.True(t, errors.Is(err, ErrNoMatch), "expected ErrNoMatch, got %v", err)
+	})
+
+	t.Run("malformed isrc is not searched for", func(t *testing.T) {
+		sc := &Client{musicAPI: "http://localhost:0", accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		_, err := sc.MatchISRC(context.Background(), "isrc:USUM7")
+		require.Error(t, err)
+	})
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query TrackQuery
+		want  string
+	}{
+		{
+			name:  "plain",
+			query: TrackQuery{Name: "Dreams", Artists: []string{"Fleetwood Mac"}},
+			want:  "Dreams Fleetwood Mac",
+		},
+		{
+			name:  "like a version with featured artist",
+			query: TrackQuery{Name: "Say Something (feat. Someone) [triple j Like A Version]", Artists: []string{"Peking Duk, Jack River"}},
+			want:  "Say Something like a version Peking Duk Jack River",
+		},
+		{
+			name:  "smart quotes and diacritics",
+			query: TrackQuery{Name: "Don’t Stop", Artists: []string{"Beyoncé"}},
+			want:  "Don't Stop Beyonce",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, searchQuery(tt.query))
+		})
+	}
+}
+
+func TestScoreCandidate(t *testing.T) {
+	query := TrackQuery{Name: "Say Something (triple j Like A Version)", Artists: []string{"Peking Duk feat. Jack River"}}
+
+	likeAVersion := scoreCandidate(query, Track{
+		Name:    "Say Something - triple j Like A Version",
+		Artists: []Artist{{Name: "Peking Duk"}, {Name: "Jack River"}},
+	})
+	original := scoreCandidate(query, Track{
+		Name:    "Say Something",
+		Artists: []Artist{{Name: "Peking Duk"}, {Name: "Jack River"}},
+	})
+	cover := scoreCandidate(query, Track{
+		Name:    "Say Something",
+		Artists: []Artist{{Name: "A Great Big World"}},
+	})
+
+	require.InDelta(t, 1, likeAVersion, 0.001)
+	require.Greater(t, likeAVersion, original)
+	require.Greater(t, original, cover)
+}
@@ -49,6 +49,36 @@ func (mr *MockClienterMockRecorder) AddSongsToPlaylist(ctx, songs, playlistId in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSongsToPlaylist", reflect.TypeOf((*MockClienter)(nil).AddSongsToPlaylist), ctx, songs, playlistId)
 }
 
+// CreatePlaylist mocks base method.
+func (m *MockClienter) CreatePlaylist(ctx context.Context, details spotify.PlaylistDetails) (spotify.Playlist, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePlaylist", ctx, details)
+	ret0, _ := ret[0].(spotify.Playlist)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePlaylist indicates an expected call of CreatePlaylist.
+func (mr *MockClienterMockRecorder) CreatePlaylist(ctx, details interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlaylist", reflect.TypeOf((*MockClienter)(nil).CreatePlaylist), ctx, details)
+}
+
+// FindPlaylist mocks base method.
+func (m *MockClienter) FindPlaylist(ctx context.Context, name string) (spotify.Playlist, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPlaylist", ctx, name)
+	ret0, _ := ret[0].(spotify.Playlist)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPlaylist indicates an expected call of FindPlaylist.
+func (mr *MockClienterMockRecorder) FindPlaylist(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPlaylist", reflect.TypeOf((*MockClienter)(nil).FindPlaylist), ctx, name)
+}
+
 // GetCurrentPlaylist mocks base method.
 func (m *MockClienter) GetCurrentPlaylist(ctx context.Context, playlistId string) ([]spotify.Track, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +94,21 @@ func (mr *MockClienterMockRecorder) GetCurrentPlaylist(ctx, playlistId interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCurrentPlaylist", reflect.TypeOf((*MockClienter)(nil).GetCurrentPlaylist), ctx, playlistId)
 }
 
+// GetTrackById mocks base method.
+func (m *MockClienter) GetTrackById(ctx context.Context, trackId string) (spotify.Track, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTrackById", ctx, trackId)
+	ret0, _ := ret[0].(spotify.Track)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTrackById indicates an expected call of GetTrackById.
+func (mr *MockClienterMockRecorder) GetTrackById(ctx, trackId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTrackById", reflect.TypeOf((*MockClienter)(nil).GetTrackById), ctx, trackId)
+}
+
 // GetTrackBySongNameAndArtist mocks base method.
 func (m *MockClienter) GetTrackBySongNameAndArtist(ctx context.Context, name string, artist []string) (spotify.Track, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +124,65 @@ func (mr *MockClienterMockRecorder) GetTrackBySongNameAndArtist(ctx, name, artis
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTrackBySongNameAndArtist", reflect.TypeOf((*MockClienter)(nil).GetTrackBySongNameAndArtist), ctx, name, artist)
 }
 
+// GrantedScopes mocks base method.
+func (m *MockClienter) GrantedScopes(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantedScopes", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GrantedScopes indicates an expected call of GrantedScopes.
+func (mr *MockClienterMockRecorder) GrantedScopes(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantedScopes", reflect.TypeOf((*MockClienter)(nil).GrantedScopes), ctx)
+}
+
+// InsertSongsIntoPlaylist mocks base method.
+func (m *MockClienter) InsertSongsIntoPlaylist(ctx context.Context, songs []string, playlistId string, position int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSongsIntoPlaylist", ctx, songs, playlistId, position)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertSongsIntoPlaylist indicates an expected call of InsertSongsIntoPlaylist.
+func (mr *MockClienterMockRecorder) InsertSongsIntoPlaylist(ctx, songs, playlistId, position interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSongsIntoPlaylist", reflect.TypeOf((*MockClienter)(nil).InsertSongsIntoPlaylist), ctx, songs, playlistId, position)
+}
+
+// MatchISRC mocks base method.
+func (m *MockClienter) MatchISRC(ctx context.Context, isrc string) (spotify.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchISRC", ctx, isrc)
+	ret0, _ := ret[0].(spotify.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MatchISRC indicates an expected call of MatchISRC.
+func (mr *MockClienterMockRecorder) MatchISRC(ctx, isrc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchISRC", reflect.TypeOf((*MockClienter)(nil).MatchISRC), ctx, isrc)
+}
+
+// MatchTrack mocks base method.
+func (m *MockClienter) MatchTrack(ctx context.Context, query spotify.TrackQuery) (spotify.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchTrack", ctx, query)
+	ret0, _ := ret[0].(spotify.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MatchTrack indicates an expected call of MatchTrack.
+func (mr *MockClienterMockRecorder) MatchTrack(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchTrack", reflect.TypeOf((*MockClienter)(nil).MatchTrack), ctx, query)
+}
+
 // RemoveSongsFromPlaylist mocks base method.
 func (m *MockClienter) RemoveSongsFromPlaylist(ctx context.Context, songs []spotify.Track, playlistId string) error {
 	m.ctrl.T.Helper()
@@ -92,3 +196,17 @@ func (mr *MockClienterMockRecorder) RemoveSongsFromPlaylist(ctx, songs, playlist
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSongsFromPlaylist", reflect.TypeOf((*MockClienter)(nil).RemoveSongsFromPlaylist), ctx, songs, playlistId)
 }
+
+// UpdatePlaylistDetails mocks base method.
+func (m *MockClienter) UpdatePlaylistDetails(ctx context.Context, playlistId string, details spotify.PlaylistDetails) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePlaylistDetails", ctx, playlistId, details)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePlaylistDetails indicates an expected call of UpdatePlaylistDetails.
+func (mr *MockClienterMockRecorder) UpdatePlaylistDetails(ctx, playlistId, details interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePlaylistDetails", reflect.TypeOf((*MockClienter)(nil).UpdatePlaylistDetails), ctx, playlistId, details)
+}
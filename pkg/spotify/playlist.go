@@ -0,0 +1,244 @@
+package spotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
+)
+
+// userPlaylistsPageSize is the most playlists spotify returns in one request
+const userPlaylistsPageSize = 50
+
+// ErrPlaylistNotFound means the current user doesn't own a playlist with the name asked for
+var ErrPlaylistNotFound = errors.New("playlist not found")
+
+type (
+	// Playlist describes a spotify playlist, without its tracks
+	Playlist struct {
+		Id            string `json:"id"`
+		Uri           string `json:"uri"`
+		Name          string `json:"name"`
+		Description   string `json:"description"`
+		Public        bool   `json:"public"`
+		Collaborative bool   `json:"collaborative"`
+		Owner         User   `json:"owner"`
+		SnapshotId    string `json:"snapshot_id"`
+	}
+
+	User struct {
+		Id          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	}
+
+	// PlaylistDetails are the settings of a playlist that can be changed. Empty strings and nil
+	// flags are left as they are, so clearing a description takes a description of " ".
+	PlaylistDetails struct {
+		Name        string `json:"name,omitempty"`
+		Description string `json:"description,omitempty"`
+		Public      *bool  `json:"public,omitempty"`
+		// Collaborative playlists can't be public, so Public must be false to set it
+		Collaborative *bool `json:"collaborative,omitempty"`
+	}
+
+	userPlaylists struct {
+		Items []Playlist `json:"items"`
+		Next  string     `json:"next"`
+	}
+)
+
+// Validate checks spotify would accept the details
+func (d PlaylistDetails) Validate() error {
+	if d.Collaborative != nil && *d.Collaborative && (d.Public == nil || *d.Public) {
+		return errors.New("a collaborative playlist must not be public")
+	}
+	return nil
+}
+
+// CurrentUser returns the user the client is authorised as
+func (sc *Client) CurrentUser(ctx context.Context) (User, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "CurrentUser")
+	defer childSpan.End()
+
+	var user User
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sc.musicAPI+"/me", nil)
+	if err != nil {
+		return user, errors.Wrap(err, "failed to create new request")
+	}
+
+	res, err := sc.Do(ctx, req)
+	if err != nil {
+		return user, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return user, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
+		return user, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	return user, nil
+}
+
+// FindPlaylist returns the first playlist owned by the current user with the given name, or
+// ErrPlaylistNotFound if the user has none. Private playlists are only seen with the
+// playlist-read-private scope.
+func (sc *Client) FindPlaylist(ctx context.Context, name string) (Playlist, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "FindPlaylist")
+	defer childSpan.End()
+
+	user, err := sc.CurrentUser(ctx)
+	if err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to get current user")
+	}
+
+	for offset := 0; ; {
+		page, err := sc.getUserPlaylistsPage(ctx, offset)
+		if err != nil {
+			return Playlist{}, errors.Wrapf(err, "failed to fetch playlists at offset %d", offset)
+		}
+		for _, playlist := range page.Items {
+			// the list includes playlists the user follows, which the bot can't edit
+			if playlist.Name == name && playlist.Owner.Id == user.Id {
+				return playlist, nil
+			}
+		}
+
+		offset += len(page.Items)
+		if page.Next == "" || len(page.Items) == 0 {
+			break
+		}
+	}
+	return Playlist{}, errors.Wrapf(ErrPlaylistNotFound, "no playlist named %q", name)
+}
+
+func (sc *Client) getUserPlaylistsPage(ctx context.Context, offset int) (*userPlaylists, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sc.musicAPI+"/me/playlists", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new request")
+	}
+
+	query := req.URL.Query()
+	query.Add("limit", strconv.Itoa(userPlaylistsPageSize))
+	query.Add("offset", strconv.Itoa(offset))
+	req.URL.RawQuery = query.Encode()
+
+	res, err := sc.Do(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	page := &userPlaylists{}
+	if err := json.NewDecoder(res.Body).Decode(page); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	return page, nil
+}
+
+// CreatePlaylist creates a playlist owned by the current user. A name is required, and the
+// playlist is public unless details say otherwise.
+func (sc *Client) CreatePlaylist(ctx context.Context, details PlaylistDetails) (Playlist, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "CreatePlaylist")
+	defer childSpan.End()
+
+	if details.Name == "" {
+		return Playlist{}, errors.New("a playlist needs a name")
+	}
+	if err := details.Validate(); err != nil {
+		return Playlist{}, err
+	}
+
+	user, err := sc.CurrentUser(ctx)
+	if err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to get current user")
+	}
+
+	requestUrl, err := url.JoinPath(sc.musicAPI, "users", user.Id, "playlists")
+	if err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to construct request url")
+	}
+	jsonData, err := json.Marshal(details)
+	if err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to marshal playlist details")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestUrl, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to create new request")
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	res, err := sc.Do(ctx, req)
+	if err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return Playlist{}, fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+
+	var playlist Playlist
+	if err := json.NewDecoder(res.Body).Decode(&playlist); err != nil {
+		return Playlist{}, errors.Wrap(err, "failed to unmarshal response body")
+	}
+	if playlist.SnapshotId != "" {
+		sc.setSnapshot(playlist.Id, playlist.SnapshotId)
+	}
+	return playlist, nil
+}
+
+// UpdatePlaylistDetails changes the name, description and flags of a playlist the current
+// user owns. Details left empty are unchanged.
+func (sc *Client) UpdatePlaylistDetails(ctx context.Context, playlistId string, details PlaylistDetails) error {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "UpdatePlaylistDetails")
+	defer childSpan.End()
+
+	if err := details.Validate(); err != nil {
+		return err
+	}
+
+	requestUrl, err := url.JoinPath(sc.musicAPI, "playlists", playlistId)
+	if err != nil {
+		return errors.Wrap(err, "failed to construct request url")
+	}
+	jsonData, err := json.Marshal(details)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal playlist details")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, requestUrl, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return errors.Wrap(err, "failed to create new request")
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	res, err := sc.Do(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CreatePlaylist(t *testing.T) {
+	public := false
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/me":
+			_, _ = w.Write([]byte(`{"id":"someuser"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/users/someuser/playlists":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"newplaylist","name":"triple j recently played","public":false,"snapshot_id":"snapshot1"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+	playlist, err := sc.CreatePlaylist(context.Background(), PlaylistDetails{Name: "triple j recently played", Public: &public})
+	require.NoError(t, err)
+	require.Equal(t, "newplaylist", playlist.Id)
+	require.Equal(t, map[string]any{"name": "triple j recently played", "public": false}, got)
+	require.Equal(t, "snapshot1", sc.snapshot("newplaylist"))
+}
+
+func TestClient_FindPlaylist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/me":
+			_, _ = w.Write([]byte(`{"id":"someuser"}`))
+		case r.URL.Query().Get("offset") == "0":
+			// a followed playlist with the same name belongs to someone else
+			_, _ = w.Write([]byte(`{"items":[
+				{"id":"followed","name":"triple j recently played","owner":{"id":"someoneelse"}},
+				{"id":"other","name":"Road trip","owner":{"id":"someuser"}}
+			],"next":"page2"}`))
+		case r.URL.Query().Get("offset") == "2":
+			_, _ = w.Write([]byte(`{"items":[{"id":"owned","name":"triple j recently played","owner":{"id":"someuser"}}]}`))
+		}
+	}))
+	defer server.Close()
+	sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+	playlist, err := sc.FindPlaylist(context.Background(), "triple j recently played")
+	require.NoError(t, err)
+	require.Equal(t, "owned", playlist.Id)
+
+	_, err = sc.FindPlaylist(context.Background(), "Double J recently played")
+	require.True(t, errors.Is(err, ErrPlaylistNotFound), "expected ErrPlaylistNotFound, got %v", err)
+}
+
+func TestClient_UpdatePlaylistDetails(t *testing.T) {
+	t.Run("sends only the details given", func(t *testing.T) {
+		var got map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPut, r.Method)
+			require.Equal(t, "/playlists/playlistId", r.URL.Path)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		}))
+		defer server.Close()
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		err := sc.UpdatePlaylistDetails(context.Background(), "playlistId", PlaylistDetails{Description: "Last updated 14:32 AEST"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"description": "Last updated 14:32 AEST"}, got)
+	})
+
+	t.Run("public playlists can't be collaborative", func(t *testing.T) {
+		sc := &Client{musicAPI: "http://localhost:0", accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+		collaborative := true
+
+		err := sc.UpdatePlaylistDetails(context.Background(), "playlistId", PlaylistDetails{Collaborative: &collaborative})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,177 @@
+package spotify
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// defaultRetryBudget is the number of retries shared by every request made in a run
+	defaultRetryBudget = 10
+	// maxAttempts caps how many times a single request is sent
+	maxAttempts = 4
+	// maxRetryAfter is the longest Retry-After we are willing to wait out
+	maxRetryAfter = time.Minute
+)
+
+// retryPolicy decides when a failed spotify request is sent again. Rate limited requests
+// honour Retry-After, while server errors back off exponentially with jitter. Every retry
+// draws from a budget shared across the run, so a struggling API can't stall the bot.
+type retryPolicy struct {
+	mu        sync.Mutex
+	budget    int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func newRetryPolicy(budget int) *retryPolicy {
+	return &retryPolicy{
+		budget:    budget,
+		baseDelay: 500 * time.Millisecond,
+		maxDelay:  10 * time.Second,
+	}
+}
+
+// isReplaySafe reports whether sending req again can't change the outcome
+func isReplaySafe(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut:
+		return true
+	}
+	return false
+}
+
+// nextDelay returns how long to wait before sending req again, and false if it shouldn't be.
+// A retry is only granted while the budget lasts.
+func (p *retryPolicy) nextDelay(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if p == nil || attempt >= maxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	if !canReplay(req) {
+		return 0, false
+	}
+
+	var delay time.Duration
+	switch {
+	case err != nil:
+		if !isReplaySafe(req) {
+			return 0, false
+		}
+		delay = p.backoff(attempt)
+	case res.StatusCode == http.StatusTooManyRequests:
+		// a rate limited request was never processed, so it's safe to send again whatever it is
+		retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"))
+		if !ok {
+			retryAfter = p.backoff(attempt)
+		}
+		if retryAfter > maxRetryAfter {
+			return 0, false
+		}
+		delay = retryAfter
+	case res.StatusCode >= http.StatusInternalServerError:
+		if !isReplaySafe(req) {
+			return 0, false
+		}
+		delay = p.backoff(attempt)
+	default:
+		return 0, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.budget <= 0 {
+		return 0, false
+	}
+	p.budget--
+	return delay, true
+}
+
+// backoff returns a random delay of up to baseDelay * 2^attempt, capped at maxDelay
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.baseDelay << attempt
+	if ceiling > p.maxDelay || ceiling <= 0 {
+		ceiling = p.maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// parseRetryAfter reads a Retry-After header given in either seconds or as an HTTP date
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// prepareRetry records the retry on the current span and readies req to be sent again
+func prepareRetry(ctx context.Context, req *http.Request, res *http.Response, err error, attempt int, delay time.Duration) error {
+	attributes := []attribute.KeyValue{
+		attribute.String("http.method", req.Method),
+		attribute.String("http.path", req.URL.Path),
+		attribute.Int("retry.attempt", attempt+1),
+		attribute.String("retry.delay", delay.String()),
+	}
+	if res != nil {
+		attributes = append(attributes, attribute.Int("http.status_code", res.StatusCode))
+		discardResponse(res)
+	}
+	if err != nil {
+		attributes = append(attributes, attribute.String("error", err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("retrying spotify request", trace.WithAttributes(attributes...))
+
+	if err := rewindBody(req); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// canReplay reports whether req can be sent again, which needs a way to rewind its body
+func canReplay(req *http.Request) bool {
+	return req.Body == nil || req.GetBody != nil
+}
+
+// rewindBody resets the body of req so it can be sent again
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// discardResponse drains and closes a response that won't be used, so the connection can be reused
+func discardResponse(res *http.Response) {
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
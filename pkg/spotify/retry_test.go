@@ -0,0 +1,136 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRetryPolicy returns a policy that backs off without slowing the tests down
+func newTestRetryPolicy(budget int) *retryPolicy {
+	return &retryPolicy{budget: budget, baseDelay: time.Millisecond, maxDelay: time.Millisecond}
+}
+
+// newFlakyServer fails the first failures requests with status before succeeding
+func newFlakyServer(t *testing.T, failures int, status int, header http.Header) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"snapshot_id":"snapshot2"}`))
+		default:
+			_, _ = w.Write([]byte(`{"uri":"spotify:track:abc","snapshot_id":"snapshot2"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClient_Do_Retries(t *testing.T) {
+	t.Run("honours Retry-After when rate limited", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}})
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+
+		track, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, "spotify:track:abc", track.Uri)
+		require.Equal(t, 2, *requests)
+	})
+
+	t.Run("retries rate limited adds", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}})
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+
+		err := sc.AddSongsToPlaylist(context.Background(), []string{"spotify:track:abc"}, "playlistId")
+		require.NoError(t, err)
+		require.Equal(t, 2, *requests)
+	})
+
+	t.Run("gives up when Retry-After is too long", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}})
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.Error(t, err)
+		require.Equal(t, 1, *requests)
+	})
+
+	t.Run("backs off on server errors", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, 3, *requests)
+	})
+
+	t.Run("does not retry adds after a server error", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 1, http.StatusInternalServerError, nil)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+
+		err := sc.AddSongsToPlaylist(context.Background(), []string{"spotify:track:abc"}, "playlistId")
+		require.Error(t, err)
+		require.Equal(t, 1, *requests)
+	})
+
+	t.Run("does not replay removals after a server error", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 1, http.StatusBadGateway, nil)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+		sc.setSnapshot("playlistId", "snapshot1")
+
+		// the playlist is re-read instead, and the flaky server's playlist no longer holds the song
+		err := sc.RemoveSongsFromPlaylist(context.Background(), []Track{{Uri: "spotify:track:abc"}}, "playlistId")
+		require.NoError(t, err)
+		require.Equal(t, 3, *requests, "expected the removal and two reads")
+	})
+
+	t.Run("stops once the budget is spent", func(t *testing.T) {
+		server, requests := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(2)}
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.Error(t, err)
+		require.Equal(t, 3, *requests)
+
+		// the budget is shared, so later requests are not retried either
+		_, err = sc.GetTrackById(context.Background(), "abc")
+		require.Error(t, err)
+		require.Equal(t, 4, *requests)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "seconds", header: "3", want: 3 * time.Second, wantOk: true},
+		{name: "date in the past", header: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOk: true},
+		{name: "missing", header: "", wantOk: false},
+		{name: "garbage", header: "soon", wantOk: false},
+		{name: "negative", header: "-1", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.header)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxRemoveAttempts is how many times a removal is tried against a changing playlist
+const maxRemoveAttempts = 3
+
+var (
+	// errSnapshotConflict means the playlist changed since it was last read
+	errSnapshotConflict = errors.New("playlist snapshot conflict")
+	// errRemoveUncertain means a removal failed without saying whether it was applied, e.g.
+	// because the response was lost. Positions are resolved against the snapshot sent, so
+	// sending it again could remove other songs.
+	errRemoveUncertain = errors.New("removal may have been applied")
+)
+
+type (
+	snapshotResponse struct {
+		SnapshotId string `json:"snapshot_id"`
+	}
+
+	// errorResponse is the body spotify sends with a failed request
+	errorResponse struct {
+		Error struct {
+			Status  int    `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+)
+
+// isSnapshotConflict reports whether spotify rejected a request because the snapshot it was
+// made against is stale. Other bad requests, e.g. a malformed body, aren't conflicts.
+func isSnapshotConflict(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusConflict:
+		return true
+	case http.StatusBadRequest:
+		var body errorResponse
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			return false
+		}
+		return strings.Contains(strings.ToLower(body.Error.Message), "snapshot")
+	default:
+		return false
+	}
+}
+
+func (sc *Client) snapshot(playlistId string) string {
+	sc.snapshotsMu.Lock()
+	defer sc.snapshotsMu.Unlock()
+	return sc.snapshots[playlistId]
+}
+
+func (sc *Client) setSnapshot(playlistId, snapshotId string) {
+	sc.snapshotsMu.Lock()
+	defer sc.snapshotsMu.Unlock()
+	if sc.snapshots == nil {
+		sc.snapshots = make(map[string]string)
+	}
+	sc.snapshots[playlistId] = snapshotId
+}
+
+// readSnapshot fetches the current snapshot_id of the playlist
+func (sc *Client) readSnapshot(ctx context.Context, playlistId string) error {
+	requestUrl, err := url.JoinPath(sc.musicAPI, "playlists", playlistId)
+	if err != nil {
+		return errors.Wrap(err, "failed to construct request url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create new request")
+	}
+
+	query := req.URL.Query()
+	query.Add("fields", "snapshot_id")
+	req.URL.RawQuery = query.Encode()
+
+	res, err := sc.Do(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code: %d", res.StatusCode)
+	}
+	return sc.updateSnapshot(res.Body, playlistId)
+}
+
+// updateSnapshot records the snapshot_id in a playlist response body
+func (sc *Client) updateSnapshot(body io.Reader, playlistId string) error {
+	snapshot := &snapshotResponse{}
+	if err := json.NewDecoder(body).Decode(snapshot); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return errors.Wrap(err, "failed to unmarshal response body")
+	}
+	if snapshot.SnapshotId != "" {
+		sc.setSnapshot(playlistId, snapshot.SnapshotId)
+	}
+	return nil
+}
+
+// remapPositions finds the songs to remove again in a freshly read playlist. Each song takes
+// the unclaimed occurrence of its track closest to where it used to be. Songs that are no
+// longer in the playlist are dropped as there is nothing left to remove.
+func remapPositions(songs []Track, currentSongs []Track) []Track {
+	claimed := make(map[int]bool, len(songs))
+	remapped := make([]Track, 0, len(songs))
+	for _, song := range songs {
+		best := -1
+		for _, current := range currentSongs {
+			if current.Uri != song.Uri || claimed[current.Position] {
+				continue
+			}
+			if best < 0 || abs(current.Position-song.Position) < abs(best-song.Position) {
+				best = current.Position
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		claimed[best] = true
+		song.Position = best
+		remapped = append(remapped, song)
+	}
+	return remapped
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
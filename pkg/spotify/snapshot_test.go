@@ -0,0 +1,189 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotConflictBody is what spotify sends when positions are removed against a stale snapshot
+const snapshotConflictBody = `{"error":{"status":400,"message":"Invalid snapshot id"}}`
+
+type removeRequest struct {
+	Tracks []struct {
+		Uri       string `json:"uri"`
+		Positions []int  `json:"positions"`
+	} `json:"tracks"`
+	SnapshotId string `json:"snapshot_id"`
+}
+
+func TestClient_RemoveSongsFromPlaylist_Positions(t *testing.T) {
+	const playlistId = "playlistId"
+
+	t.Run("removes positions against the snapshot read", func(t *testing.T) {
+		var got removeRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/playlists/"+playlistId:
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot1"}`))
+			case r.Method == http.MethodGet:
+				_, _ = w.Write([]byte(`{"items":[{"track":{"uri":"uri:a"}},{"track":{"uri":"uri:b"}},{"track":{"uri":"uri:a"}}]}`))
+			case r.Method == http.MethodDelete:
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot2"}`))
+			}
+		}))
+		defer server.Close()
+
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		songs, err := sc.GetCurrentPlaylist(context.Background(), playlistId)
+		require.NoError(t, err)
+
+		// only the first of the two copies of uri:a is removed
+		err = sc.RemoveSongsFromPlaylist(context.Background(), songs[:1], playlistId)
+		require.NoError(t, err)
+
+		require.Equal(t, "snapshot1", got.SnapshotId)
+		require.Len(t, got.Tracks, 1)
+		require.Equal(t, "uri:a", got.Tracks[0].Uri)
+		require.Equal(t, []int{0}, got.Tracks[0].Positions)
+		require.Equal(t, "snapshot2", sc.snapshot(playlistId))
+	})
+
+	t.Run("re-reads the playlist after a conflict", func(t *testing.T) {
+		var deletes []removeRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/playlists/"+playlistId:
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot2"}`))
+			case r.Method == http.MethodGet:
+				// someone has added uri:c to the top of the playlist
+				_, _ = w.Write([]byte(`{"items":[{"track":{"uri":"uri:c"}},{"track":{"uri":"uri:a"}},{"track":{"uri":"uri:b"}}]}`))
+			case r.Method == http.MethodDelete:
+				var body removeRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				deletes = append(deletes, body)
+				if body.SnapshotId != "snapshot2" {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(snapshotConflictBody))
+					return
+				}
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot3"}`))
+			}
+		}))
+		defer server.Close()
+
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+		sc.setSnapshot(playlistId, "snapshot1")
+
+		err := sc.RemoveSongsFromPlaylist(context.Background(), []Track{{Uri: "uri:a", Position: 0}}, playlistId)
+		require.NoError(t, err)
+
+		require.Len(t, deletes, 2)
+		require.Equal(t, []int{0}, deletes[0].Tracks[0].Positions)
+		require.Equal(t, []int{1}, deletes[1].Tracks[0].Positions)
+		require.Equal(t, "snapshot3", sc.snapshot(playlistId))
+	})
+
+	t.Run("re-reads the playlist after a lost response", func(t *testing.T) {
+		var deletes []removeRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/playlists/"+playlistId:
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot2"}`))
+			case r.Method == http.MethodGet:
+				// the first removal was applied, so only the other copy of uri:a is left
+				_, _ = w.Write([]byte(`{"items":[{"track":{"uri":"uri:b"}},{"track":{"uri":"uri:a"}}]}`))
+			case r.Method == http.MethodDelete:
+				var body removeRequest
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Error(err)
+				}
+				deletes = append(deletes, body)
+				if len(deletes) == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot3"}`))
+			}
+		}))
+		defer server.Close()
+
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient, retries: newTestRetryPolicy(5)}
+		sc.setSnapshot(playlistId, "snapshot1")
+
+		err := sc.RemoveSongsFromPlaylist(context.Background(), []Track{{Uri: "uri:a", Position: 0}}, playlistId)
+		require.NoError(t, err)
+
+		// the second removal is against the playlist read after the failure, not a replay
+		require.Len(t, deletes, 2)
+		require.Equal(t, "snapshot1", deletes[0].SnapshotId)
+		require.Equal(t, "snapshot2", deletes[1].SnapshotId)
+		require.Equal(t, []int{1}, deletes[1].Tracks[0].Positions)
+	})
+
+	t.Run("gives up after repeated conflicts", func(t *testing.T) {
+		var deletes int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/playlists/"+playlistId:
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot1"}`))
+			case r.Method == http.MethodGet:
+				_, _ = w.Write([]byte(`{"items":[{"track":{"uri":"uri:a"}}]}`))
+			case r.Method == http.MethodDelete:
+				deletes++
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(snapshotConflictBody))
+			}
+		}))
+		defer server.Close()
+
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+
+		err := sc.RemoveSongsFromPlaylist(context.Background(), []Track{{Uri: "uri:a"}}, playlistId)
+		require.Error(t, err)
+		require.Equal(t, maxRemoveAttempts, deletes)
+	})
+
+	t.Run("reports other bad requests without retrying", func(t *testing.T) {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"status":400,"message":"Invalid track uri: uri:a"}}`))
+		}))
+		defer server.Close()
+
+		sc := &Client{musicAPI: server.URL, accessToken: "someaccesstoken", httpClient: http.DefaultClient}
+		sc.setSnapshot(playlistId, "snapshot1")
+
+		err := sc.RemoveSongsFromPlaylist(context.Background(), []Track{{Uri: "uri:a"}}, playlistId)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, errSnapshotConflict)
+		require.ErrorContains(t, err, "invalid status code: 400")
+		require.Equal(t, 1, requests)
+	})
+}
+
+func TestRemapPositions(t *testing.T) {
+	currentSongs := []Track{
+		{Uri: "uri:new", Position: 0},
+		{Uri: "uri:a", Position: 1},
+		{Uri: "uri:b", Position: 2},
+		{Uri: "uri:a", Position: 3},
+	}
+
+	got := remapPositions([]Track{
+		{Uri: "uri:a", Position: 4},
+		{Uri: "uri:a", Position: 0},
+		{Uri: "uri:gone", Position: 1},
+	}, currentSongs)
+
+	// each song takes the closest copy not already claimed, and songs no longer in the playlist are dropped
+	require.Equal(t, []Track{{Uri: "uri:a", Position: 3}, {Uri: "uri:a", Position: 1}}, got)
+}
@@ -8,27 +8,45 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
 	"github.com/JamesBLewis/triplej-playlist-generator/pkg/telemetry"
 )
 
 const (
 	ContentType = "application/json; charset=UTF-8"
-	Market      = "AU"
+	// Market is the default market tracks must be playable in, see WithMarket
+	Market = "AU"
+	// playlistPageSize is the most playlist items spotify returns in one request
+	playlistPageSize = 100
+	// playlistTrackFields selects the parts of each playlist item that make up a Track
+	playlistTrackFields = "next,items(added_at,track(uri,name,duration_ms,explicit,popularity,is_local,is_playable," +
+		"linked_from(id,uri),artists(id,uri,name),album(id,uri,name,release_date)))"
 )
 
 type (
 	Clienter interface {
 		GetCurrentPlaylist(ctx context.Context, playlistId string) ([]Track, error)
 		GetTrackBySongNameAndArtist(ctx context.Context, name string, artist []string) (Track, error)
+		MatchTrack(ctx context.Context, query TrackQuery) (Match, error)
+		MatchISRC(ctx context.Context, isrc string) (Match, error)
+		GetTrackById(ctx context.Context, trackId string) (Track, error)
 		RemoveSongsFromPlaylist(ctx context.Context, songs []Track, playlistId string) error
 		AddSongsToPlaylist(ctx context.Context, songs []string, playlistId string) error
+		InsertSongsIntoPlaylist(ctx context.Context, songs []string, playlistId string, position int) error
+		FindPlaylist(ctx context.Context, name string) (Playlist, error)
+		CreatePlaylist(ctx context.Context, details PlaylistDetails) (Playlist, error)
+		UpdatePlaylistDetails(ctx context.Context, playlistId string, details PlaylistDetails) error
+		GrantedScopes(ctx context.Context) ([]string, error)
 	}
 
 	Client struct {
@@ -39,18 +57,76 @@ type (
 		clientSecret string
 		refreshToken string
 		httpClient   *http.Client
-	}
+		// tokenExpiry is when accessToken runs out, zero when unknown
+		tokenExpiry time.Time
+		// tokenMu guards the access token and the refresh in flight
+		tokenMu        sync.Mutex
+		pendingRefresh *tokenRefresh
+		// snapshots holds the last snapshot_id seen for each playlist
+		snapshots   map[string]string
+		snapshotsMu sync.Mutex
+		// retries decides which failed requests are sent again, nil disables retrying
+		retries *retryPolicy
+		// matchThreshold is the lowest confidence MatchTrack accepts
+		matchThreshold float64
+		// market is the country tracks must be playable in
+		market string
+		// tokenStore keeps tokens between runs, nil to keep them in memory only
+		tokenStore  TokenStore
+		tokenLoaded bool
+		// scope is the space separated scopes the access token was granted, empty when unknown
+		scope string
+		// seed fingerprints the configured refresh token the tokens came from, see Token.Seed
+		seed string
+		// log reports problems that don't fail the request, nil to print them instead
+		log log.Log
+	}
+
+	// Option configures optional settings on a Client
+	Option func(*Client)
 
 	PlaylistTracks struct {
 		Items []PlaylistTrackItem `json:"items"`
+		// Next is the url of the next page of tracks, empty on the last page
+		Next string `json:"next"`
 	}
 
 	PlaylistTrackItem struct {
-		Track Track `json:"track"`
+		// AddedAt is when the track was added to the playlist
+		AddedAt time.Time `json:"added_at"`
+		Track   Track     `json:"track"`
 	}
 
 	Track struct {
-		Uri string `json:"uri"`
+		Uri        string   `json:"uri"`
+		Name       string   `json:"name"`
+		Artists    []Artist `json:"artists"`
+		Album      Album    `json:"album"`
+		DurationMs int      `json:"duration_ms"`
+		Explicit   bool     `json:"explicit"`
+		Popularity int      `json:"popularity"`
+		IsLocal    bool     `json:"is_local"`
+		// IsPlayable is only reported when tracks are requested for a market, nil otherwise
+		IsPlayable *bool `json:"is_playable,omitempty"`
+		// LinkedFrom is the track spotify relinked to this one for the market, nil if it wasn't relinked
+		LinkedFrom *LinkedTrack `json:"linked_from,omitempty"`
+		// AddedAt is when the track was added to the playlist it was read from
+		AddedAt time.Time `json:"-"`
+		// Position is the index of the track in the playlist it was read from
+		Position int `json:"-"`
+	}
+
+	Artist struct {
+		Id   string `json:"id"`
+		Uri  string `json:"uri"`
+		Name string `json:"name"`
+	}
+
+	Album struct {
+		Id          string `json:"id"`
+		Uri         string `json:"uri"`
+		Name        string `json:"name"`
+		ReleaseDate string `json:"release_date"`
 	}
 
 	TokenRefreshResponse struct {
@@ -58,6 +134,8 @@ type (
 		TokenType   string `json:"token_type"`
 		Scope       string `json:"scope"`
 		ExpiresIn   int    `json:"expires_in"`
+		// RefreshToken is only returned when spotify rotates the refresh token
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	SearchTracksResponse struct {
@@ -65,19 +143,22 @@ type (
 	}
 
 	SearchTracks struct {
-		Items []SearchTrackItem `json:"items"`
-	}
-
-	SearchTrackItem struct {
-		Uri  string `json:"uri"`
-		Name string `json:"name"`
+		Items []Track `json:"items"`
 	}
 )
 
 //go:generate mockgen -destination=mocks/spotify.go -source=spotify.go
 
-func NewSpotifyClient(clientId, clientSecret, refreshToken string) Clienter {
-	return &Client{
+// WithRetryBudget sets how many times failed requests may be retried in total over the
+// life of the client. A budget of 0 disables retrying.
+func WithRetryBudget(budget int) Option {
+	return func(sc *Client) {
+		sc.retries = newRetryPolicy(budget)
+	}
+}
+
+func NewSpotifyClient(clientId, clientSecret, refreshToken string, opts ...Option) Clienter {
+	sc := &Client{
 		musicAPI:     "https://api.spotify.com/v1",
 		accountAPI:   "https://accounts.spotify.com/api",
 		clientId:     clientId,
@@ -85,24 +166,73 @@ func NewSpotifyClient(clientId, clientSecret, refreshToken string) Clienter {
 		refreshToken: refreshToken,
 		accessToken:  "",
 		// yes this is an arbitrary timeout I've pulled out of thin air
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		retries:        newRetryPolicy(defaultRetryBudget),
+		matchThreshold: DefaultMatchThreshold,
+		market:         Market,
 	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	return sc
 }
 
-// Do wraps httpClient.Do and injects an access token into the request's header
-func (sc *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	// if no access token is set, refresh it.
-	if sc.accessToken == "" {
-		if err := sc.refreshAccessToken(ctx); err != nil {
-			return nil, err
-		}
+// ArtistNames returns the names of the artists credited on the track
+func (t Track) ArtistNames() []string {
+	names := make([]string, 0, len(t.Artists))
+	for _, artist := range t.Artists {
+		names = append(names, artist.Name)
 	}
+	return names
+}
 
-	// Set the Authorization header to use the new access token
-	req.Header.Set("Authorization", "Bearer "+sc.accessToken)
+// String describes the track as "Artist, Artist - Name", falling back to its uri when it has no name
+func (t Track) String() string {
+	if t.Name == "" {
+		return t.Uri
+	}
+	return strings.Join(t.ArtistNames(), ", ") + " - " + t.Name
+}
 
-	// Now send the request using the http.Client
-	return sc.httpClient.Do(req)
+// Do wraps httpClient.Do and injects an access token into the request's header. The token
+// is refreshed shortly before it expires, and once more if spotify rejects it. Requests that
+// are rate limited or hit a server error are retried while the retry budget lasts.
+func (sc *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	token, err := sc.getAccessToken(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	reauthorised := false
+	for attempt := 0; ; {
+		// Set the Authorization header to use the current access token
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		// Now send the request using the http.Client
+		res, err := sc.httpClient.Do(req)
+
+		// the token may have been revoked or expired early, so refresh it and replay the request once
+		if err == nil && res.StatusCode == http.StatusUnauthorized && !reauthorised && canReplay(req) {
+			reauthorised = true
+			discardResponse(res)
+			if token, err = sc.getAccessToken(ctx, token); err != nil {
+				return nil, err
+			}
+			if err := rewindBody(req); err != nil {
+				return nil, errors.Wrap(err, "failed to replay request")
+			}
+			continue
+		}
+
+		delay, retry := sc.retries.nextDelay(req, res, err, attempt)
+		if !retry {
+			return res, err
+		}
+		if err := prepareRetry(ctx, req, res, err, attempt, delay); err != nil {
+			return nil, errors.Wrap(err, "failed to retry request")
+		}
+		attempt++
+	}
 }
 
 func (sc *Client) refreshAccessToken(ctx context.Context) error {
@@ -111,10 +241,13 @@ func (sc *Client) refreshAccessToken(ctx context.Context) error {
 	defer childSpan.End()
 	fmt.Println("Refreshing Spotify access token...")
 
-	encodedIdAndSecret := base64.StdEncoding.EncodeToString([]byte(sc.clientId + ":" + sc.clientSecret))
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", sc.refreshToken)
+	// tokens from the PKCE flow are refreshed with just the client id
+	if sc.clientSecret == "" {
+		data.Set("client_id", sc.clientId)
+	}
 
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, sc.accountAPI+"/token", strings.NewReader(data.Encode()))
 	if err != nil {
@@ -122,7 +255,10 @@ func (sc *Client) refreshAccessToken(ctx context.Context) error {
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("Authorization", "Basic "+encodedIdAndSecret)
+	if sc.clientSecret != "" {
+		encodedIdAndSecret := base64.StdEncoding.EncodeToString([]byte(sc.clientId + ":" + sc.clientSecret))
+		req.Header.Add("Authorization", "Basic "+encodedIdAndSecret)
+	}
 	req.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
 
 	res, err := sc.httpClient.Do(req)
@@ -140,10 +276,23 @@ func (sc *Client) refreshAccessToken(ctx context.Context) error {
 		return errors.Wrap(err, "failed to unmarshal response body")
 	}
 
-	sc.accessToken = tokenRefreshResponse.AccessToken
+	// the old refresh token may stop working once spotify has rotated it
+	if tokenRefreshResponse.RefreshToken != "" {
+		sc.refreshToken = tokenRefreshResponse.RefreshToken
+	}
+	sc.setAccessToken(tokenRefreshResponse.AccessToken, time.Duration(tokenRefreshResponse.ExpiresIn)*time.Second, tokenRefreshResponse.Scope)
+
+	// the new token is good for this run either way, so failing to keep it isn't fatal, but
+	// every run will refresh it again until it can be saved
+	if err := sc.saveToken(ctx); err != nil {
+		childSpan.RecordError(err)
+		sc.reportError(ctx, "failed to save spotify token, check SPOTIFY_TOKEN_FILE is writable", err)
+	}
 	return nil
 }
 
+// GetCurrentPlaylist returns every track in the playlist in playlist order, paging through
+// the playlist until spotify reports there is no next page.
 func (sc *Client) GetCurrentPlaylist(ctx context.Context, playlistId string) ([]Track, error) {
 	// Add a child span
 	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "GetCurrentPlaylist")
@@ -152,15 +301,48 @@ func (sc *Client) GetCurrentPlaylist(ctx context.Context, playlistId string) ([]
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to construct request url")
 	}
+
+	// read the snapshot first, so any edit made while paging is caught when removing songs
+	if err := sc.readSnapshot(ctx, playlistId); err != nil {
+		return nil, err
+	}
+
+	var songs []Track
+	for offset := 0; ; {
+		playlistTracks, err := sc.getPlaylistPage(ctx, requestUrl, offset)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch playlist page at offset %d", offset)
+		}
+
+		for _, item := range playlistTracks.Items {
+			track := item.Track
+			// edits must name the track the playlist holds, not its relink
+			track.Uri = track.playlistUri()
+			track.AddedAt = item.AddedAt
+			track.Position = len(songs)
+			songs = append(songs, track)
+		}
+
+		offset += len(playlistTracks.Items)
+		if playlistTracks.Next == "" || len(playlistTracks.Items) == 0 {
+			break
+		}
+	}
+	return songs, nil
+}
+
+func (sc *Client) getPlaylistPage(ctx context.Context, requestUrl string, offset int) (*PlaylistTracks, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create new request")
 	}
 
-	// Add the fields and limit parameter to the request
+	// Add the fields, limit and offset parameters to the request
 	query := req.URL.Query()
-	query.Add("fields", "items(track.uri)")
-	query.Add("limit", "50")
+	query.Add("fields", playlistTrackFields)
+	query.Add("market", sc.marketCode())
+	query.Add("limit", strconv.Itoa(playlistPageSize))
+	query.Add("offset", strconv.Itoa(offset))
 	req.URL.RawQuery = query.Encode()
 
 	res, err := sc.Do(ctx, req)
@@ -177,28 +359,35 @@ func (sc *Client) GetCurrentPlaylist(ctx context.Context, playlistId string) ([]
 	if err := json.NewDecoder(res.Body).Decode(playlistTracks); err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal response body")
 	}
+	return playlistTracks, nil
+}
 
-	var songs []Track
-	for _, item := range playlistTracks.Items {
-		songs = append(songs, Track{Uri: item.Track.Uri})
+// GetTrackBySongNameAndArtist returns the track that best matches the name and artists.
+// See MatchTrack for how candidates are scored.
+func (sc *Client) GetTrackBySongNameAndArtist(ctx context.Context, name string, artists []string) (Track, error) {
+	match, err := sc.MatchTrack(ctx, TrackQuery{Name: name, Artists: artists})
+	if err != nil {
+		return Track{}, err
 	}
-	return songs, nil
+	return match.Track, nil
 }
 
-func (sc *Client) GetTrackBySongNameAndArtist(ctx context.Context, name string, artists []string) (Track, error) {
+// GetTrackById looks up a track directly from its Spotify ID, e.g. one linked by the ABC.
+func (sc *Client) GetTrackById(ctx context.Context, trackId string) (Track, error) {
 	// Add a child span
-	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "GetTrackBySongNameAndArtist")
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "GetTrackById")
 	defer childSpan.End()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sc.musicAPI+"/search", nil)
+	requestUrl, err := url.JoinPath(sc.musicAPI, "tracks", trackId)
+	if err != nil {
+		return Track{}, errors.Wrap(err, "failed to construct request url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
 	if err != nil {
 		return Track{}, errors.Wrap(err, "failed to create new request")
 	}
 
 	query := req.URL.Query()
-	query.Add("q", fmt.Sprintf("%s %s", name, strings.Join(artists, ", ")))
-	query.Add("type", "track")
-	query.Add("market", Market)
-	query.Add("limit", "1")
+	query.Add("market", sc.marketCode())
 	req.URL.RawQuery = query.Encode()
 
 	res, err := sc.Do(ctx, req)
@@ -211,35 +400,99 @@ func (sc *Client) GetTrackBySongNameAndArtist(ctx context.Context, name string,
 		return Track{}, fmt.Errorf("invalid status code: %d", res.StatusCode)
 	}
 
-	searchTracksResponse := &SearchTracksResponse{}
-	if err := json.NewDecoder(res.Body).Decode(searchTracksResponse); err != nil {
+	track := &Track{}
+	if err := json.NewDecoder(res.Body).Decode(track); err != nil {
 		return Track{}, errors.Wrap(err, "failed to unmarshal response body")
 	}
-
-	if len(searchTracksResponse.Tracks.Items) == 0 {
-		return Track{}, fmt.Errorf("could not find track: %s %s", name, strings.Join(artists, ", "))
+	if len(track.Uri) == 0 {
+		return Track{}, fmt.Errorf("could not find track: %s", trackId)
+	}
+	if !track.Playable() {
+		return Track{}, errors.Wrapf(ErrUnplayable, "track %s in %s", trackId, sc.marketCode())
 	}
-	return Track{
-		Uri: searchTracksResponse.Tracks.Items[0].Uri,
-	}, nil
+	return *track, nil
 }
 
+// RemoveSongsFromPlaylist removes the songs at their positions in the playlist. The songs
+// should come from GetCurrentPlaylist, so their positions are checked against the snapshot of
+// the playlist that was read. If the playlist has changed since, it is re-read and the songs
+// are found again before retrying.
+//
+// Spotify takes at most 100 tracks per request, so larger removals are sent in chunks starting
+// from the end of the playlist. Removing a chunk then never moves the songs of the chunks after
+// it. If a chunk fails the error is a *ChunkError, and the chunks before it have been removed.
 func (sc *Client) RemoveSongsFromPlaylist(ctx context.Context, songs []Track, playlistId string) error {
 	// Add a child span
 	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "RemoveSongsFromPlaylist")
 	defer childSpan.End()
-	if len(songs) == 0 {
-		return nil
+
+	songs = slices.Clone(songs)
+	slices.SortStableFunc(songs, func(a, b Track) int {
+		return b.Position - a.Position
+	})
+
+	chunks := chunk(songs, maxItemsPerRequest)
+	removed := 0
+	for i, songs := range chunks {
+		if err := sc.removeChunk(ctx, songs, playlistId); err != nil {
+			return &ChunkError{Op: "remove", Chunk: i, Chunks: len(chunks), Applied: removed, Err: err}
+		}
+		removed += len(songs)
 	}
+	return nil
+}
+
+// removeChunk removes up to maxItemsPerRequest songs, re-reading the playlist if it changed
+// since the songs' positions were read or the removal may have been applied.
+func (sc *Client) removeChunk(ctx context.Context, songs []Track, playlistId string) error {
+	childSpan := trace.SpanFromContext(ctx)
+	for attempt := 1; len(songs) > 0; attempt++ {
+		err := sc.removePositions(ctx, songs, playlistId)
+		if attempt == maxRemoveAttempts {
+			return err
+		}
+		switch {
+		case errors.Is(err, errSnapshotConflict):
+			childSpan.AddEvent("playlist changed since it was read, re-reading playlist")
+		case errors.Is(err, errRemoveUncertain):
+			childSpan.AddEvent("removal may have been applied, re-reading playlist")
+		default:
+			return err
+		}
 
+		currentSongs, err := sc.GetCurrentPlaylist(ctx, playlistId)
+		if err != nil {
+			return errors.Wrap(err, "failed to re-read playlist after snapshot conflict")
+		}
+		songs = remapPositions(songs, currentSongs)
+	}
+	return nil
+}
+
+func (sc *Client) removePositions(ctx context.Context, songs []Track, playlistId string) error {
 	// Create a struct to hold the tracks data
+	type removeTrack struct {
+		Uri       string `json:"uri"`
+		Positions []int  `json:"positions"`
+	}
 	type playlistData struct {
-		Tracks []Track `json:"tracks"`
+		Tracks     []removeTrack `json:"tracks"`
+		SnapshotId string        `json:"snapshot_id,omitempty"`
+	}
+
+	// Fill the struct with our songs data, grouping the positions of repeated songs
+	data := playlistData{SnapshotId: sc.snapshot(playlistId)}
+	trackIndex := make(map[string]int, len(songs))
+	for _, song := range songs {
+		index, ok := trackIndex[song.Uri]
+		if !ok {
+			index = len(data.Tracks)
+			trackIndex[song.Uri] = index
+			data.Tracks = append(data.Tracks, removeTrack{Uri: song.Uri})
+		}
+		data.Tracks[index].Positions = append(data.Tracks[index].Positions, song.Position)
 	}
 
-	// Fill the struct with our songs data
-	data := playlistData{Tracks: songs}
-
 	// Marshal the struct into JSON
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -253,33 +506,85 @@ func (sc *Client) RemoveSongsFromPlaylist(ctx context.Context, songs []Track, pl
 
 	req.Header.Set("Content-Type", ContentType)
 
+	// the removal isn't sent again after a lost response or server error, as spotify would
+	// apply the positions to the snapshot sent again. The playlist is re-read instead.
 	res, err := sc.Do(ctx, req)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute request")
+		return errors.Wrapf(errRemoveUncertain, "failed to execute request: %v", err)
 	}
+	defer res.Body.Close()
 
+	// spotify rejects positions that don't hold the given track in the given snapshot
+	if isSnapshotConflict(res) {
+		return errors.Wrapf(errSnapshotConflict, "invalid status code: %d", res.StatusCode)
+	}
+	if res.StatusCode >= http.StatusInternalServerError {
+		return errors.Wrapf(errRemoveUncertain, "invalid status code: %d", res.StatusCode)
+	}
 	if res.StatusCode != http.StatusOK {
 		return fmt.Errorf("invalid status code: %d", res.StatusCode)
 	}
 
-	return nil
+	return sc.updateSnapshot(res.Body, playlistId)
 }
 
+// AddSongsToPlaylist appends the songs to the end of the playlist in order. Spotify takes at
+// most 100 tracks per request, so larger additions are sent in chunks. If a chunk fails the
+// error is a *ChunkError, and the chunks before it have been added.
 func (sc *Client) AddSongsToPlaylist(ctx context.Context, songs []string, playlistId string) error {
 	// Add a child span
 	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "AddSongsToPlaylist")
 	defer childSpan.End()
-	if len(songs) == 0 {
-		return nil
+
+	return sc.addChunks(ctx, songs, playlistId, nil)
+}
+
+// InsertSongsIntoPlaylist inserts the songs in order at position in the playlist, e.g. 0 puts
+// them at the top. Like AddSongsToPlaylist, larger insertions are sent in chunks and a failed
+// chunk is reported with a *ChunkError.
+func (sc *Client) InsertSongsIntoPlaylist(ctx context.Context, songs []string, playlistId string, position int) error {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "InsertSongsIntoPlaylist")
+	defer childSpan.End()
+
+	if position < 0 {
+		return errors.Errorf("invalid playlist position: %d", position)
 	}
+	return sc.addChunks(ctx, songs, playlistId, &position)
+}
 
-	jsonList, err := json.Marshal(songs)
+// addChunks adds the songs in chunks of maxItemsPerRequest. With a nil position every chunk is
+// appended; otherwise each chunk is inserted after the ones before it, so the songs end up in
+// order starting at position.
+func (sc *Client) addChunks(ctx context.Context, songs []string, playlistId string, position *int) error {
+	chunks := chunk(songs, maxItemsPerRequest)
+	added := 0
+	for i, songs := range chunks {
+		var chunkPosition *int
+		if position != nil {
+			p := *position + added
+			chunkPosition = &p
+		}
+		if err := sc.addSongs(ctx, songs, playlistId, chunkPosition); err != nil {
+			return &ChunkError{Op: "add", Chunk: i, Chunks: len(chunks), Applied: added, Err: err}
+		}
+		added += len(songs)
+	}
+	return nil
+}
+
+func (sc *Client) addSongs(ctx context.Context, songs []string, playlistId string, position *int) error {
+	type playlistData struct {
+		Uris     []string `json:"uris"`
+		Position *int     `json:"position,omitempty"`
+	}
+
+	jsonData, err := json.Marshal(playlistData{Uris: songs, Position: position})
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal songs")
 	}
-	jsonData := fmt.Sprintf(`{"uris":%s}`, string(jsonList))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/playlists/%s/tracks", sc.musicAPI, playlistId), bytes.NewBuffer([]byte(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/playlists/%s/tracks", sc.musicAPI, playlistId), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return errors.Wrap(err, "failed to create new request")
 	}
@@ -296,5 +601,5 @@ func (sc *Client) AddSongsToPlaylist(ctx context.Context, songs []string, playli
 		return fmt.Errorf("invalid status code: %d", res.StatusCode)
 	}
 
-	return nil
+	return sc.updateSnapshot(res.Body, playlistId)
 }
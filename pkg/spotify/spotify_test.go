@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -95,16 +97,38 @@ func TestClient_GetCurrentPlaylist(t *testing.T) {
 		}
 
 		testTrack := "spotify:track:2I66eI2j2ZfOe9q8TMLPbj"
-
-		want := []Track{{Uri: testTrack}}
+		playable := true
+
+		want := []Track{{
+			Uri:        testTrack,
+			Name:       "The Duck Song",
+			Artists:    []Artist{{Id: "duck", Uri: "spotify:artist:duck", Name: "The Duck"}},
+			Album:      Album{Id: "lemonade", Uri: "spotify:album:lemonade", Name: "Lemonade Stand", ReleaseDate: "2009-01-01"},
+			DurationMs: 191000,
+			Explicit:   false,
+			Popularity: 42,
+			IsPlayable: &playable,
+			AddedAt:    time.Date(2024, 7, 25, 10, 34, 15, 0, time.UTC),
+		}}
 
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == fmt.Sprintf("/playlists/%s", args.playlistId) {
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot1"}`))
+				return
+			}
 			if r.URL.Path != fmt.Sprintf("/playlists/%s/tracks", args.playlistId) {
 				t.Errorf("Expected to request '/playlists/%s/tracks', got: %s", args.playlistId, r.URL.Path)
 			}
 
+			if !strings.Contains(r.URL.Query().Get("fields"), "added_at") {
+				t.Errorf("Expected added_at to be requested, got fields: %s", r.URL.Query().Get("fields"))
+			}
+
 			w.WriteHeader(http.StatusOK)
-			_, err := w.Write([]byte(fmt.Sprintf(`{"items":[{"track":{"uri":"%s"}}]}`, testTrack)))
+			_, err := w.Write([]byte(fmt.Sprintf(`{"items":[{"added_at":"2024-07-25T10:34:15Z","track":{"uri":"%s","name":"The Duck Song",`+
+				`"artists":[{"id":"duck","uri":"spotify:artist:duck","name":"The Duck"}],`+
+				`"album":{"id":"lemonade","uri":"spotify:album:lemonade","name":"Lemonade Stand","release_date":"2009-01-01"},`+
+				`"duration_ms":191000,"explicit":false,"popularity":42,"is_local":false,"is_playable":true}}]}`, testTrack)))
 			if err != nil {
 				t.Error(err)
 			}
@@ -124,6 +148,85 @@ func TestClient_GetCurrentPlaylist(t *testing.T) {
 		got, err := sc.GetCurrentPlaylist(args.ctx, args.playlistId)
 		require.NoError(t, err)
 		require.Equal(t, want, got)
+		require.Equal(t, "snapshot1", sc.snapshot(args.playlistId))
+	})
+}
+
+func TestClient_GetCurrentPlaylist_Pagination(t *testing.T) {
+	const (
+		playlistId = "somePlaylistId"
+		totalItems = 250
+	)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == fmt.Sprintf("/playlists/%s", playlistId) {
+			_, _ = w.Write([]byte(`{"snapshot_id":"snapshot1"}`))
+			return
+		}
+		requests++
+		if r.URL.Path != fmt.Sprintf("/playlists/%s/tracks", playlistId) {
+			t.Errorf("Expected to request '/playlists/%s/tracks', got: %s", playlistId, r.URL.Path)
+		}
+
+		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		require.NoError(t, err)
+
+		var items []string
+		for i := offset; i < offset+limit && i < totalItems; i++ {
+			items = append(items, fmt.Sprintf(`{"track":{"uri":"spotify:track:%d"}}`, i))
+		}
+		next := "null"
+		if offset+limit < totalItems {
+			next = fmt.Sprintf(`"%s%s?offset=%d&limit=%d"`, "http://", r.Host+r.URL.Path, offset+limit, limit)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write([]byte(fmt.Sprintf(`{"items":[%s],"next":%s}`, strings.Join(items, ","), next)))
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	sc := &Client{
+		accountAPI:   server.URL,
+		musicAPI:     server.URL,
+		accessToken:  "someaccesstoken",
+		clientId:     "123",
+		clientSecret: "456",
+		refreshToken: "789",
+		httpClient:   http.DefaultClient,
+	}
+
+	got, err := sc.GetCurrentPlaylist(context.Background(), playlistId)
+	require.NoError(t, err)
+	require.Equal(t, 3, requests, "expected one request per page")
+	require.Len(t, got, totalItems)
+	for i, track := range got {
+		require.Equal(t, fmt.Sprintf("spotify:track:%d", i), track.Uri, "expected tracks in playlist order")
+		require.Equal(t, i, track.Position)
+	}
+
+	t.Run("error on a later page", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == fmt.Sprintf("/playlists/%s", playlistId) {
+				_, _ = w.Write([]byte(`{"snapshot_id":"snapshot1"}`))
+				return
+			}
+			if r.URL.Query().Get("offset") != "0" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[{"track":{"uri":"spotify:track:0"}}],"next":"more"}`))
+		}))
+		defer server.Close()
+
+		sc.musicAPI = server.URL
+		_, err := sc.GetCurrentPlaylist(context.Background(), playlistId)
+		require.Error(t, err)
 	})
 }
 
@@ -149,7 +252,7 @@ func TestClient_GetTrackBySongNameAndArtist(t *testing.T) {
 			}
 
 			w.WriteHeader(http.StatusOK)
-			_, err := w.Write([]byte(fmt.Sprintf(`{"tracks":{"items":[{"uri":"%s","name":"%s"}]}}`, "spotify:track:2I66eI2j2ZfOe9q8TMLPbj", "The Duck Song")))
+			_, err := w.Write([]byte(fmt.Sprintf(`{"tracks":{"items":[{"uri":"%s","name":"%s","artists":[{"name":"%s"}]}]}}`, "spotify:track:2I66eI2j2ZfOe9q8TMLPbj", "The Duck Song", "The Duck")))
 			if err != nil {
 				t.Error(err)
 			}
@@ -167,7 +270,9 @@ func TestClient_GetTrackBySongNameAndArtist(t *testing.T) {
 		}
 
 		want := Track{
-			"spotify:track:2I66eI2j2ZfOe9q8TMLPbj",
+			Uri:     "spotify:track:2I66eI2j2ZfOe9q8TMLPbj",
+			Name:    "The Duck Song",
+			Artists: []Artist{{Name: "The Duck"}},
 		}
 
 		got, err := sc.GetTrackBySongNameAndArtist(args.ctx, args.name, args.artist)
@@ -178,6 +283,63 @@ func TestClient_GetTrackBySongNameAndArtist(t *testing.T) {
 	})
 }
 
+func TestClient_GetTrackById(t *testing.T) {
+	testCtx := context.Background()
+	trackId := "2I66eI2j2ZfOe9q8TMLPbj"
+
+	t.Run("get track", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != fmt.Sprintf("/tracks/%s", trackId) {
+				t.Errorf("Expected to request '/tracks/%s', got: %s", trackId, r.URL.Path)
+			}
+			if r.URL.Query().Get("market") != Market {
+				t.Errorf("Expected market %s, got: %s", Market, r.URL.Query().Get("market"))
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(fmt.Sprintf(`{"uri":"spotify:track:%s","name":"The Duck Song"}`, trackId)))
+			if err != nil {
+				t.Error(err)
+			}
+		}))
+		defer server.Close()
+
+		sc := &Client{
+			accountAPI:   server.URL,
+			musicAPI:     server.URL,
+			accessToken:  "someaccesstoken",
+			clientId:     "123",
+			clientSecret: "456",
+			refreshToken: "789",
+			httpClient:   http.DefaultClient,
+		}
+
+		got, err := sc.GetTrackById(testCtx, trackId)
+		require.NoError(t, err)
+		require.Equal(t, Track{Uri: "spotify:track:" + trackId, Name: "The Duck Song"}, got)
+	})
+
+	t.Run("unknown track", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		sc := &Client{
+			accountAPI:   server.URL,
+			musicAPI:     server.URL,
+			accessToken:  "someaccesstoken",
+			clientId:     "123",
+			clientSecret: "456",
+			refreshToken: "789",
+			httpClient:   http.DefaultClient,
+		}
+
+		_, err := sc.GetTrackById(testCtx, trackId)
+		require.Error(t, err)
+	})
+}
+
 func TestClient_RemoveSongsFromPlaylist(t *testing.T) {
 	testCtx := context.Background()
 
@@ -190,7 +352,7 @@ func TestClient_RemoveSongsFromPlaylist(t *testing.T) {
 	t.Run("test delete", func(t *testing.T) {
 		args := args{
 			testCtx,
-			[]Track{{"spotify:track:2I66eI2j2ZfOe9q8TMLPbj"}},
+			[]Track{{Uri: "spotify:track:2I66eI2j2ZfOe9q8TMLPbj"}},
 			"playlistId",
 		}
 
@@ -308,10 +470,13 @@ func TestNewSpotifyClient(t *testing.T) {
 		clientId     string
 		clientSecret string
 		refreshToken string
+		opts         []Option
 	}
 	tests := []struct {
-		name string
-		args args
+		name        string
+		args        args
+		wantRetries *retryPolicy
+		wantMarket  string
 	}{
 		{
 			name: "normal initialization",
@@ -320,6 +485,30 @@ func TestNewSpotifyClient(t *testing.T) {
 				clientSecret: "secret",
 				refreshToken: "4321",
 			},
+			wantRetries: newRetryPolicy(defaultRetryBudget),
+			wantMarket:  Market,
+		},
+		{
+			name: "custom retry budget",
+			args: args{
+				clientId:     "1234",
+				clientSecret: "secret",
+				refreshToken: "4321",
+				opts:         []Option{WithRetryBudget(3)},
+			},
+			wantRetries: newRetryPolicy(3),
+			wantMarket:  Market,
+		},
+		{
+			name: "custom market",
+			args: args{
+				clientId:     "1234",
+				clientSecret: "secret",
+				refreshToken: "4321",
+				opts:         []Option{WithMarket("NZ")},
+			},
+			wantRetries: newRetryPolicy(defaultRetryBudget),
+			wantMarket:  "NZ",
 		},
 	}
 	for _, tt := range tests {
@@ -332,9 +521,12 @@ func TestNewSpotifyClient(t *testing.T) {
 				refreshToken: tt.args.refreshToken,
 				accessToken:  "",
 				// yes this is an arbitrary timeout I've pulled out of thin air
-				httpClient: &http.Client{Timeout: 10 * time.Second},
+				httpClient:     &http.Client{Timeout: 10 * time.Second},
+				retries:        tt.wantRetries,
+				matchThreshold: DefaultMatchThreshold,
+				market:         tt.wantMarket,
 			}
-			if got := NewSpotifyClient(tt.args.clientId, tt.args.clientSecret, tt.args.refreshToken); !reflect.DeepEqual(got, want) {
+			if got := NewSpotifyClient(tt.args.clientId, tt.args.clientSecret, tt.args.refreshToken, tt.args.opts...); !reflect.DeepEqual(got, want) {
 				t.Errorf("NewSpotifyClient() = %v, want %v", got, want)
 			}
 		})
@@ -0,0 +1,182 @@
+package spotify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/log"
+)
+
+// tokenExpiryMargin is how long before it expires that an access token is refreshed, so a
+// token doesn't run out while a request is in flight.
+const tokenExpiryMargin = time.Minute
+
+// tokenRefresh is a refresh in flight that concurrent callers wait on instead of starting their own
+type tokenRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// WithTokenStore keeps the access token and refresh token in store between runs, so a run
+// can reuse the last access token and a rotated refresh token isn't lost. A refresh token
+// in the store is used over the one the client was created with, unless the client was
+// created with a different refresh token than the stored tokens came from.
+func WithTokenStore(store TokenStore) Option {
+	return func(sc *Client) {
+		sc.tokenStore = store
+	}
+}
+
+// WithLogger reports problems that don't fail a request, such as a token that couldn't be
+// saved, to logger.
+func WithLogger(logger log.Log) Option {
+	return func(sc *Client) {
+		sc.log = logger
+	}
+}
+
+// reportError logs an error that doesn't fail the request being made
+func (sc *Client) reportError(ctx context.Context, msg string, err error) {
+	if sc.log == nil {
+		fmt.Println(msg+":", err)
+		return
+	}
+	sc.log.RuntimeError(ctx, msg, err)
+}
+
+// loadToken reads the token store the first time a token is needed. Callers must hold tokenMu.
+func (sc *Client) loadToken(ctx context.Context) error {
+	if sc.tokenLoaded || sc.tokenStore == nil {
+		return nil
+	}
+
+	token, err := sc.tokenStore.Load(ctx)
+	if err != nil && !errors.Is(err, ErrNoToken) {
+		return errors.Wrap(err, "failed to load token")
+	}
+	sc.tokenLoaded = true
+
+	// a new configured refresh token, e.g. after access was revoked, replaces the stored tokens
+	configured := tokenSeed(sc.refreshToken)
+	if configured != "" && token.Seed != "" && token.Seed != configured {
+		sc.seed = configured
+		return nil
+	}
+	sc.seed = token.Seed
+	if configured != "" {
+		sc.seed = configured
+	}
+
+	if token.RefreshToken != "" {
+		sc.refreshToken = token.RefreshToken
+	}
+	if token.AccessToken != "" {
+		sc.accessToken = token.AccessToken
+		sc.tokenExpiry = token.Expiry
+		sc.scope = token.Scope
+	}
+	return nil
+}
+
+// tokenSeed fingerprints a configured refresh token, without keeping the token itself
+func tokenSeed(refreshToken string) string {
+	if refreshToken == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// saveToken writes the current tokens to the token store
+func (sc *Client) saveToken(ctx context.Context) error {
+	if sc.tokenStore == nil {
+		return nil
+	}
+
+	sc.tokenMu.Lock()
+	token := Token{AccessToken: sc.accessToken, RefreshToken: sc.refreshToken, Expiry: sc.tokenExpiry, Scope: sc.scope, Seed: sc.seed}
+	sc.tokenMu.Unlock()
+	return sc.tokenStore.Save(ctx, token)
+}
+
+// setAccessToken records a new access token, when it expires and the scopes it was granted. A
+// zero expiry means the token is used until spotify rejects it.
+func (sc *Client) setAccessToken(token string, expiresIn time.Duration, scope string) {
+	sc.tokenMu.Lock()
+	defer sc.tokenMu.Unlock()
+	sc.accessToken = token
+	sc.scope = scope
+	sc.tokenExpiry = time.Time{}
+	if expiresIn > 0 {
+		sc.tokenExpiry = time.Now().Add(expiresIn)
+	}
+}
+
+// GrantedScopes returns the scopes the access token was granted, getting a token first if
+// there isn't one yet. It is nil when spotify didn't say, e.g. for a token saved before
+// scopes were kept.
+func (sc *Client) GrantedScopes(ctx context.Context) ([]string, error) {
+	if _, err := sc.getAccessToken(ctx, ""); err != nil {
+		return nil, err
+	}
+	sc.tokenMu.Lock()
+	defer sc.tokenMu.Unlock()
+	return strings.Fields(sc.scope), nil
+}
+
+// validToken reports whether the access token can be used. Callers must hold tokenMu.
+func (sc *Client) validToken(rejected string) bool {
+	if sc.accessToken == "" || sc.accessToken == rejected {
+		return false
+	}
+	return sc.tokenExpiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(sc.tokenExpiry)
+}
+
+// getAccessToken returns an access token that is good to use, refreshing it when there is
+// none, it is about to expire or it is the rejected token spotify just refused. Simultaneous
+// callers share a single refresh.
+func (sc *Client) getAccessToken(ctx context.Context, rejected string) (string, error) {
+	sc.tokenMu.Lock()
+	if err := sc.loadToken(ctx); err != nil {
+		sc.tokenMu.Unlock()
+		return "", err
+	}
+	if sc.validToken(rejected) {
+		token := sc.accessToken
+		sc.tokenMu.Unlock()
+		return token, nil
+	}
+
+	refresh := sc.pendingRefresh
+	if refresh == nil {
+		refresh = &tokenRefresh{done: make(chan struct{})}
+		sc.pendingRefresh = refresh
+		sc.tokenMu.Unlock()
+
+		refresh.err = sc.refreshAccessToken(ctx)
+
+		sc.tokenMu.Lock()
+		sc.pendingRefresh = nil
+		close(refresh.done)
+	}
+	sc.tokenMu.Unlock()
+
+	select {
+	case <-refresh.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if refresh.err != nil {
+		return "", refresh.err
+	}
+
+	sc.tokenMu.Lock()
+	defer sc.tokenMu.Unlock()
+	return sc.accessToken, nil
+}
@@ -0,0 +1,211 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenServer serves the token endpoint, handing out token1, token2 and so on, and a
+// track endpoint that only accepts the tokens in valid.
+func newTokenServer(t *testing.T, expiresIn int, valid ...string) (*httptest.Server, *int32) {
+	t.Helper()
+	var refreshes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			n := atomic.AddInt32(&refreshes, 1)
+			// give concurrent callers time to pile up behind the refresh
+			time.Sleep(10 * time.Millisecond)
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token":"token%d","expires_in":%d}`, n, expiresIn)))
+			return
+		}
+		for _, token := range valid {
+			if r.Header.Get("Authorization") == "Bearer "+token {
+				_, _ = w.Write([]byte(`{"uri":"spotify:track:abc"}`))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server, &refreshes
+}
+
+func TestClient_Do_Token(t *testing.T) {
+	t.Run("records when the token expires", func(t *testing.T) {
+		server, _ := newTokenServer(t, 3600, "token1")
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, httpClient: http.DefaultClient}
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, "token1", sc.accessToken)
+		require.WithinDuration(t, time.Now().Add(time.Hour), sc.tokenExpiry, time.Minute)
+	})
+
+	t.Run("refreshes a token about to expire", func(t *testing.T) {
+		server, refreshes := newTokenServer(t, 3600, "token1")
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, httpClient: http.DefaultClient}
+		sc.setAccessToken("stale", time.Second, "")
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), *refreshes)
+		require.Equal(t, "token1", sc.accessToken)
+	})
+
+	t.Run("refreshes and replays after a 401", func(t *testing.T) {
+		server, refreshes := newTokenServer(t, 3600, "token1")
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, httpClient: http.DefaultClient}
+		sc.setAccessToken("revoked", time.Hour, "")
+
+		track, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, "spotify:track:abc", track.Uri)
+		require.Equal(t, int32(1), *refreshes)
+	})
+
+	t.Run("only replays once", func(t *testing.T) {
+		server, refreshes := newTokenServer(t, 3600)
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, httpClient: http.DefaultClient}
+		sc.setAccessToken("revoked", time.Hour, "")
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.Error(t, err)
+		require.Equal(t, int32(1), *refreshes)
+	})
+
+	t.Run("collapses concurrent refreshes", func(t *testing.T) {
+		server, refreshes := newTokenServer(t, 3600, "token1")
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, httpClient: http.DefaultClient}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := sc.GetTrackById(context.Background(), "abc")
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), *refreshes)
+	})
+}
+
+// memoryTokenStore keeps a token in memory and counts how often it is saved
+type memoryTokenStore struct {
+	mu    sync.Mutex
+	token *Token
+	saves int
+}
+
+func (s *memoryTokenStore) Load(context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return Token{}, ErrNoToken
+	}
+	return *s.token, nil
+}
+
+func (s *memoryTokenStore) Save(_ context.Context, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = &token
+	s.saves++
+	return nil
+}
+
+func TestClient_Do_TokenStore(t *testing.T) {
+	t.Run("reuses the stored access token", func(t *testing.T) {
+		server, refreshes := newTokenServer(t, 3600, "storedtoken")
+		store := &memoryTokenStore{token: &Token{AccessToken: "storedtoken", RefreshToken: "somerefreshtoken", Expiry: time.Now().Add(time.Hour)}}
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, httpClient: http.DefaultClient}
+		WithTokenStore(store)(sc)
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, int32(0), *refreshes)
+		require.Equal(t, 0, store.saves)
+	})
+
+	t.Run("saves refreshed and rotated tokens", func(t *testing.T) {
+		var refreshTokens []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				require.NoError(t, r.ParseForm())
+				refreshTokens = append(refreshTokens, r.Form.Get("refresh_token"))
+				_, _ = w.Write([]byte(`{"access_token":"token1","refresh_token":"rotatedtoken","expires_in":3600,"scope":"playlist-read-private playlist-modify-public"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"uri":"spotify:track:abc"}`))
+		}))
+		defer server.Close()
+		// the stored refresh token is newer than the one the client was created with
+		store := &memoryTokenStore{token: &Token{AccessToken: "expiredtoken", RefreshToken: "storedtoken", Expiry: time.Now().Add(-time.Hour)}}
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, refreshToken: "configuredtoken", httpClient: http.DefaultClient}
+		WithTokenStore(store)(sc)
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, []string{"storedtoken"}, refreshTokens)
+		require.Equal(t, 1, store.saves)
+		require.Equal(t, "token1", store.token.AccessToken)
+		require.Equal(t, "rotatedtoken", store.token.RefreshToken)
+		require.WithinDuration(t, time.Now().Add(time.Hour), store.token.Expiry, time.Minute)
+		require.Equal(t, "playlist-read-private playlist-modify-public", store.token.Scope)
+		require.Equal(t, tokenSeed("configuredtoken"), store.token.Seed)
+
+		scopes, err := sc.GrantedScopes(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"playlist-read-private", "playlist-modify-public"}, scopes)
+	})
+
+	t.Run("a new configured refresh token replaces the stored tokens", func(t *testing.T) {
+		var refreshTokens []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+				}
+				refreshTokens = append(refreshTokens, r.Form.Get("refresh_token"))
+				_, _ = w.Write([]byte(`{"access_token":"token1","expires_in":3600}`))
+				return
+			}
+			if got := r.Header.Get("Authorization"); got != "Bearer token1" {
+				t.Errorf("unexpected authorization header %q", got)
+			}
+			_, _ = w.Write([]byte(`{"uri":"spotify:track:abc"}`))
+		}))
+		defer server.Close()
+		// the stored tokens came from a refresh token that has since been revoked and replaced
+		store := &memoryTokenStore{token: &Token{AccessToken: "revokedtoken", RefreshToken: "rotatedtoken", Expiry: time.Now().Add(time.Hour), Seed: tokenSeed("oldtoken")}}
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, refreshToken: "newtoken", httpClient: http.DefaultClient}
+		WithTokenStore(store)(sc)
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, []string{"newtoken"}, refreshTokens)
+		require.Equal(t, "newtoken", store.token.RefreshToken)
+		require.Equal(t, tokenSeed("newtoken"), store.token.Seed)
+	})
+
+	t.Run("the stored tokens are kept while the configured refresh token is unchanged", func(t *testing.T) {
+		server, refreshes := newTokenServer(t, 3600, "storedtoken")
+		store := &memoryTokenStore{token: &Token{AccessToken: "storedtoken", RefreshToken: "rotatedtoken", Expiry: time.Now().Add(time.Hour), Seed: tokenSeed("configuredtoken")}}
+		sc := &Client{musicAPI: server.URL, accountAPI: server.URL, refreshToken: "configuredtoken", httpClient: http.DefaultClient}
+		WithTokenStore(store)(sc)
+
+		_, err := sc.GetTrackById(context.Background(), "abc")
+		require.NoError(t, err)
+		require.Equal(t, int32(0), *refreshes)
+		require.Equal(t, "rotatedtoken", sc.refreshToken)
+	})
+}
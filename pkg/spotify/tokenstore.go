@@ -0,0 +1,184 @@
+package spotify
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TokenKeySize is the size of the AES-256 key an EncryptedFileTokenStore needs
+const TokenKeySize = 32
+
+// ErrNoToken means the token store hasn't had a token saved to it yet
+var ErrNoToken = errors.New("no token stored")
+
+type (
+	// Token is what a token store keeps between runs
+	Token struct {
+		AccessToken  string `json:"access_token,omitempty"`
+		RefreshToken string `json:"refresh_token"`
+		// Expiry is when AccessToken runs out, zero when unknown
+		Expiry time.Time `json:"expiry"`
+		// Scope is the space separated scopes AccessToken was granted, empty when unknown
+		Scope string `json:"scope,omitempty"`
+		// Seed fingerprints the configured refresh token these tokens came from, so a new one
+		// replaces them. It is empty when they came from the auth command.
+		Seed string `json:"seed,omitempty"`
+	}
+
+	// TokenStore keeps spotify tokens between runs. Implementations are safe to use from
+	// several goroutines.
+	TokenStore interface {
+		// Load returns the stored token, or ErrNoToken if there isn't one
+		Load(ctx context.Context) (Token, error)
+		Save(ctx context.Context, token Token) error
+	}
+
+	// FileTokenStore keeps the token as JSON in a file only its owner can read
+	FileTokenStore struct {
+		path string
+		mu   sync.Mutex
+	}
+
+	// EncryptedFileTokenStore keeps the token in a file encrypted with AES-GCM, for when the
+	// file sits somewhere others can read, e.g. a shared volume.
+	EncryptedFileTokenStore struct {
+		path string
+		aead cipher.AEAD
+		mu   sync.Mutex
+	}
+)
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Load(_ context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var token Token
+	data, err := readTokenFile(s.path)
+	if err != nil {
+		return token, err
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return token, errors.Wrap(err, "failed to unmarshal token file")
+	}
+	return token, nil
+}
+
+func (s *FileTokenStore) Save(_ context.Context, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal token")
+	}
+	return writeTokenFile(s.path, data)
+}
+
+// NewEncryptedFileTokenStore returns a store that encrypts the token with key, which must be
+// TokenKeySize random bytes.
+func NewEncryptedFileTokenStore(path string, key []byte) (*EncryptedFileTokenStore, error) {
+	if len(key) != TokenKeySize {
+		return nil, errors.Errorf("token key must be %d bytes, got %d", TokenKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	return &EncryptedFileTokenStore{path: path, aead: aead}, nil
+}
+
+func (s *EncryptedFileTokenStore) Load(_ context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var token Token
+	data, err := readTokenFile(s.path)
+	if err != nil {
+		return token, err
+	}
+
+	// the file is the nonce followed by the sealed token
+	if len(data) < s.aead.NonceSize() {
+		return token, errors.New("token file is too short")
+	}
+	nonce, sealed := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return token, errors.Wrap(err, "failed to decrypt token file, is the key right?")
+	}
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return token, errors.Wrap(err, "failed to unmarshal token file")
+	}
+	return token, nil
+}
+
+func (s *EncryptedFileTokenStore) Save(_ context.Context, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal token")
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "failed to read random bytes")
+	}
+	return writeTokenFile(s.path, s.aead.Seal(nonce, nonce, plaintext, nil))
+}
+
+func readTokenFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoToken
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read token file")
+	}
+	return data, nil
+}
+
+// writeTokenFile replaces the token file in one step, so another process reading it never
+// sees half a token. The file is only readable by its owner.
+func writeTokenFile(path string, data []byte) error {
+	// CreateTemp makes the file with 0600 permissions
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create token file")
+	}
+	// a no-op once the file has been renamed into place
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write token file")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write token file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write token file")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to replace token file")
+	}
+	return nil
+}
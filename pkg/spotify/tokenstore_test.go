@@ -0,0 +1,95 @@
+package spotify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStore(path)
+
+	_, err := store.Load(ctx)
+	require.True(t, errors.Is(err, ErrNoToken), "expected ErrNoToken, got %v", err)
+
+	token := Token{AccessToken: "someaccesstoken", RefreshToken: "somerefreshtoken", Expiry: time.Date(2024, time.June, 3, 4, 32, 0, 0, time.UTC)}
+	require.NoError(t, store.Save(ctx, token))
+
+	got, err := store.Load(ctx)
+	require.NoError(t, err)
+	require.Equal(t, token, got)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestFileTokenStore_ConcurrentSaves(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewFileTokenStore(filepath.Join(dir, "token.json"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.NoError(t, store.Save(ctx, Token{RefreshToken: fmt.Sprintf("refreshtoken%d", i)}))
+		}(i)
+	}
+	wg.Wait()
+
+	// whichever save won, the file holds a whole token and no temporary files are left behind
+	token, err := store.Load(ctx)
+	require.NoError(t, err)
+	require.Regexp(t, `^refreshtoken\d$`, token.RefreshToken)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token.bin")
+	key := bytes.Repeat([]byte{7}, TokenKeySize)
+
+	store, err := NewEncryptedFileTokenStore(path, key)
+	require.NoError(t, err)
+
+	_, err = store.Load(ctx)
+	require.True(t, errors.Is(err, ErrNoToken), "expected ErrNoToken, got %v", err)
+
+	token := Token{AccessToken: "someaccesstoken", RefreshToken: "somerefreshtoken", Expiry: time.Date(2024, time.June, 3, 4, 32, 0, 0, time.UTC)}
+	require.NoError(t, store.Save(ctx, token))
+
+	got, err := store.Load(ctx)
+	require.NoError(t, err)
+	require.Equal(t, token, got)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "somerefreshtoken")
+
+	t.Run("wrong key", func(t *testing.T) {
+		other, err := NewEncryptedFileTokenStore(path, bytes.Repeat([]byte{8}, TokenKeySize))
+		require.NoError(t, err)
+
+		_, err = other.Load(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("short key", func(t *testing.T) {
+		_, err := NewEncryptedFileTokenStore(path, []byte("tooshort"))
+		require.Error(t, err)
+	})
+}
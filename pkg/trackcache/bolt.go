@@ -0,0 +1,105 @@
+package trackcache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket holds the entries, keyed by song key
+var boltBucket = []byte("tracks")
+
+// BoltCache keeps entries in a bbolt database file, so they last between runs
+type BoltCache struct {
+	options
+	db *bolt.DB
+}
+
+// OpenBoltCache opens the cache file at path, creating it if needed, and drops any entries
+// that have expired since it was last used. The file is locked while it is open.
+func OpenBoltCache(path string, opts ...Option) (*BoltCache, error) {
+	// give up rather than wait forever if another run has the file open
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open track cache")
+	}
+
+	c := &BoltCache{options: newOptions(opts), db: db}
+	if err := c.prune(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// prune deletes expired entries
+func (c *BoltCache) prune() error {
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+
+		var expired [][]byte
+		err = bucket.ForEach(func(key, value []byte) error {
+			var entry Entry
+			// entries that can't be read are as good as expired
+			if json.Unmarshal(value, &entry) != nil || c.expired(entry) {
+				expired = append(expired, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "failed to prune track cache")
+}
+
+func (c *BoltCache) Get(_ context.Context, key string) (Entry, bool, error) {
+	var value []byte
+	err := c.db.View(func(tx *bolt.Tx) error {
+		// the value is only valid during the transaction, so copy it out
+		value = append(value, tx.Bucket(boltBucket).Get([]byte(key))...)
+		return nil
+	})
+	if err != nil {
+		return Entry{}, false, errors.Wrap(err, "failed to read track cache")
+	}
+	if value == nil {
+		return Entry{}, false, nil
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return Entry{}, false, errors.Wrap(err, "failed to unmarshal track cache entry")
+	}
+	if c.expired(entry) {
+		return Entry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+func (c *BoltCache) Put(_ context.Context, key string, entry Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal track cache entry")
+	}
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+	return errors.Wrap(err, "failed to write track cache")
+}
+
+func (c *BoltCache) Close() error {
+	return c.db.Close()
+}
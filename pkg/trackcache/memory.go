@@ -0,0 +1,43 @@
+package trackcache
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryCache keeps entries in memory, so they only last as long as the process
+type MemoryCache struct {
+	options
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func NewMemoryCache(opts ...Option) *MemoryCache {
+	return &MemoryCache{options: newOptions(opts), entries: make(map[string]Entry)}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) (Entry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	if c.expired(entry) {
+		delete(c.entries, key)
+		return Entry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+func (c *MemoryCache) Put(_ context.Context, key string, entry Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+func (c *MemoryCache) Close() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package trackcache remembers which spotify track each radio song resolved to, so songs
+// that are still in the recent plays aren't searched for again on every run.
+package trackcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+)
+
+// DefaultTTL is how long a resolved song is trusted before it is looked up again
+const DefaultTTL = 7 * 24 * time.Hour
+
+type (
+	// Entry is a song resolved to a spotify track
+	Entry struct {
+		Track      spotify.Track `json:"track"`
+		Confidence float64       `json:"confidence"`
+		// Method is how the song was resolved, e.g. spotify.MatchMethodSearch
+		Method     string    `json:"method"`
+		ResolvedAt time.Time `json:"resolved_at"`
+	}
+
+	// Cache stores entries by song key until they are older than its TTL. Implementations are
+	// safe to use from several goroutines.
+	Cache interface {
+		// Get returns the entry for key, and false if there isn't one or it has expired
+		Get(ctx context.Context, key string) (Entry, bool, error)
+		Put(ctx context.Context, key string, entry Entry) error
+		Close() error
+	}
+
+	// Option configures optional behaviour of a cache
+	Option func(*options)
+
+	options struct {
+		ttl time.Duration
+		now func() time.Time
+	}
+)
+
+// WithTTL sets how long entries are kept, which defaults to DefaultTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithClock sets the clock entries are aged by, e.g. in tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{ttl: DefaultTTL, now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// expired reports whether the entry is older than the TTL
+func (o options) expired(entry Entry) bool {
+	return o.now().Sub(entry.ResolvedAt) >= o.ttl
+}
@@ -0,0 +1,95 @@
+package trackcache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/JamesBLewis/triplej-playlist-generator/pkg/spotify"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 9, 14, 8, 0, 0, 0, time.UTC)
+	entry := Entry{
+		Track:      spotify.Track{Uri: "uri:tongueTied", Name: "Tongue Tied", Artists: []spotify.Artist{{Name: "Marshmello"}}},
+		Confidence: 0.9,
+		Method:     spotify.MatchMethodSearch,
+		ResolvedAt: now,
+	}
+
+	tests := []struct {
+		name string
+		open func(t *testing.T, opts ...Option) Cache
+	}{
+		{
+			name: "memory",
+			open: func(t *testing.T, opts ...Option) Cache {
+				return NewMemoryCache(opts...)
+			},
+		},
+		{
+			name: "bolt",
+			open: func(t *testing.T, opts ...Option) Cache {
+				cache, err := OpenBoltCache(filepath.Join(t.TempDir(), "track-cache.db"), opts...)
+				require.NoError(t, err)
+				return cache
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := now
+			cache := tt.open(t, WithTTL(time.Hour), WithClock(func() time.Time { return clock }))
+			defer cache.Close()
+
+			_, ok, err := cache.Get(ctx, "arid:mt8dZ1N3Qa")
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			require.NoError(t, cache.Put(ctx, "arid:mt8dZ1N3Qa", entry))
+			got, ok, err := cache.Get(ctx, "arid:mt8dZ1N3Qa")
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, entry, got)
+
+			clock = now.Add(time.Hour)
+			_, ok, err = cache.Get(ctx, "arid:mt8dZ1N3Qa")
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	}
+}
+
+func TestBoltCache_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "track-cache.db")
+	now := time.Date(2024, 9, 14, 8, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+
+	cache, err := OpenBoltCache(path, clock)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put(ctx, "fresh", Entry{Track: spotify.Track{Uri: "uri:fresh"}, ResolvedAt: now}))
+	require.NoError(t, cache.Put(ctx, "stale", Entry{Track: spotify.Track{Uri: "uri:stale"}, ResolvedAt: now.Add(-DefaultTTL)}))
+	require.NoError(t, cache.Close())
+
+	cache, err = OpenBoltCache(path, clock)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	entry, ok, err := cache.Get(ctx, "fresh")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "uri:fresh", entry.Track.Uri)
+
+	// expired entries are pruned when the file is opened
+	err = cache.db.View(func(tx *bolt.Tx) error {
+		require.Nil(t, tx.Bucket(boltBucket).Get([]byte("stale")))
+		return nil
+	})
+	require.NoError(t, err)
+}
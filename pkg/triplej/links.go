@@ -0,0 +1,96 @@
+package triplej
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	spotifyProvider     = "spotify"
+	musicBrainzProvider = "musicbrainz"
+	// spotifyIdLength is the length of the base62 IDs Spotify gives tracks
+	spotifyIdLength = 22
+)
+
+// SpotifyTrackId returns the Spotify track ID the ABC has linked to this song,
+// or an empty string when the ABC has no Spotify link for it.
+func (s RadioSong) SpotifyTrackId() string {
+	for _, link := range s.Links {
+		if !strings.EqualFold(link.Provider, spotifyProvider) {
+			continue
+		}
+		if id := spotifyTrackIdFromUrl(link.Url); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// spotifyTrackIdFromUrl extracts the track ID from either an open.spotify.com
+// track URL or a spotify:track: URI. Links to albums or artists are ignored, as
+// is anything that isn't a valid Spotify ID since it ends up in an API path.
+func spotifyTrackIdFromUrl(rawUrl string) string {
+	if id, ok := strings.CutPrefix(rawUrl, "spotify:track:"); ok {
+		if !isSpotifyId(id) {
+			return ""
+		}
+		return id
+	}
+
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || !isSpotifyHost(parsed.Hostname()) {
+		return ""
+	}
+
+	// paths look like /track/{id} or /intl-en/track/{id}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == "track" && isSpotifyId(segments[i+1]) {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+// isSpotifyHost reports whether hostname is spotify.com or one of its subdomains
+func isSpotifyHost(hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	return hostname == "spotify.com" || strings.HasSuffix(hostname, ".spotify.com")
+}
+
+// isSpotifyId reports whether id is a 22 character base62 Spotify ID
+func isSpotifyId(id string) bool {
+	if len(id) != spotifyIdLength {
+		return false
+	}
+	for _, r := range id {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// MusicBrainzRecordingId returns the MusicBrainz recording ID (MBID) the ABC has linked to
+// this song, or an empty string when there is no MusicBrainz recording link.
+func (s RadioSong) MusicBrainzRecordingId() string {
+	for _, link := range s.Links {
+		if !strings.EqualFold(link.Provider, musicBrainzProvider) || !strings.Contains(link.Url, "/recording/") {
+			continue
+		}
+		if link.IdComponent != "" {
+			return link.IdComponent
+		}
+		_, id, _ := strings.Cut(link.Url, "/recording/")
+		if id, _, _ = strings.Cut(id, "?"); id != "" {
+			return strings.Trim(id, "/")
+		}
+	}
+	return ""
+}
+
+// cleanISRC upper cases an ISRC and drops the hyphens and spaces it is often written with,
+// e.g. "au-ab1-23-45678" becomes "AUAB12345678".
+func cleanISRC(isrc string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isrc))
+}
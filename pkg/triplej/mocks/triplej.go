@@ -7,6 +7,7 @@ package mock_triplej
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	triplej "github.com/JamesBLewis/triplej-playlist-generator/pkg/triplej"
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,21 @@ func (m *MockClienter) EXPECT() *MockClienterMockRecorder {
 	return m.recorder
 }
 
+// FetchSongsBetween mocks base method.
+func (m *MockClienter) FetchSongsBetween(ctx context.Context, from, to time.Time) ([]triplej.RadioSong, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchSongsBetween", ctx, from, to)
+	ret0, _ := ret[0].([]triplej.RadioSong)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchSongsBetween indicates an expected call of FetchSongsBetween.
+func (mr *MockClienterMockRecorder) FetchSongsBetween(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSongsBetween", reflect.TypeOf((*MockClienter)(nil).FetchSongsBetween), ctx, from, to)
+}
+
 // FetchSongsFromTriplejAPI mocks base method.
 func (m *MockClienter) FetchSongsFromTriplejAPI(ctx context.Context, playlistSize int) ([]triplej.RadioSong, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,58 @@
+package triplej
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Station is the identifier the ABC plays API uses for a radio station.
+type Station string
+
+const (
+	TripleJ   Station = "triplej"
+	DoubleJ   Station = "doublej"
+	Unearthed Station = "unearthed"
+	Hottest   Station = "hottest"
+	Classic   Station = "classic"
+)
+
+// Stations lists every station the ABC plays API is known to serve.
+var Stations = []Station{TripleJ, DoubleJ, Unearthed, Hottest, Classic}
+
+// ParseStation converts a station identifier such as "doublej" into a Station.
+// An empty identifier defaults to triplej.
+func ParseStation(id string) (Station, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return TripleJ, nil
+	}
+	for _, station := range Stations {
+		if Station(id) == station {
+			return station, nil
+		}
+	}
+	return "", errors.Errorf("unknown ABC station: %s", id)
+}
+
+func (s Station) String() string {
+	return string(s)
+}
+
+// DisplayName is the name the ABC brands the station with, e.g. "triple j".
+func (s Station) DisplayName() string {
+	switch s {
+	case TripleJ:
+		return "triple j"
+	case DoubleJ:
+		return "Double J"
+	case Unearthed:
+		return "triple j Unearthed"
+	case Hottest:
+		return "triple j Hottest"
+	case Classic:
+		return "ABC Classic"
+	default:
+		return s.String()
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
@@ -14,29 +17,142 @@ import (
 )
 
 const (
-	abcRadioAPIBaseURL = "https://music.abcradio.net.au/api/v1/plays/search.json?station=triplej"
+	abcRadioAPIBaseURL = "https://music.abcradio.net.au/api/v1/plays/search.json"
+	// abcPageSize is the number of plays requested per page when paging through a time window
+	abcPageSize = 100
+	// defaultTimeout matches the timeout used for spotify requests
+	defaultTimeout   = 10 * time.Second
+	defaultUserAgent = "triplej-playlist-generator"
 )
 
-type Client struct{}
+type Client struct {
+	station    Station
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+	// timeout is applied to httpClient once every option has run, zero to leave it as is
+	timeout time.Duration
+}
+
+// Option configures optional behaviour of a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at a different plays search endpoint, e.g. a test server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the http.Client used to call the ABC plays API.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of each request to the ABC plays API.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent to the ABC plays API.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
 
 type RadioSong struct {
 	Id      string
 	Name    string
 	Artists []string
+	Station Station
+	// PlayId is the ABC identifier of this particular play of the recording.
+	PlayId     string
+	PlayedTime time.Time
+	// Duration is zero when the ABC doesn't know how long the recording is.
+	Duration time.Duration
+	// ISRC is the International Standard Recording Code of the recording, empty when unknown.
+	ISRC     string
+	Releases []Release
+	Artwork  []Artwork
+	Links    []Link
+}
+
+// PlayKey identifies a single play of a recording, so a song aired twice has two keys while
+// the same play listed twice has one. Without a play id, the recording and the time it was
+// played stand in for it.
+func (s RadioSong) PlayKey() string {
+	if s.PlayId != "" {
+		return s.PlayId
+	}
+	recording := s.Id
+	if recording == "" {
+		recording = s.Name + " - " + strings.Join(s.Artists, ", ")
+	}
+	return recording + "@" + s.PlayedTime.UTC().Format(time.RFC3339)
+}
+
+// Release is an album, EP or single the recording was released on.
+type Release struct {
+	Id          string    `json:"arid"`
+	Title       string    `json:"title"`
+	Format      string    `json:"format"`
+	ReleaseYear string    `json:"release_year"`
+	Artwork     []Artwork `json:"artwork"`
+	Links       []Link    `json:"links"`
+}
+
+type Artwork struct {
+	Id    string        `json:"arid"`
+	Url   string        `json:"url"`
+	Type  string        `json:"type"`
+	Sizes []ArtworkSize `json:"sizes"`
+}
+
+type ArtworkSize struct {
+	Url    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Link is an external reference the ABC holds for a recording or release, e.g. a Spotify or MusicBrainz entry.
+type Link struct {
+	Id          string `json:"arid"`
+	Url         string `json:"url"`
+	Title       string `json:"title"`
+	Type        string `json:"type"`
+	Provider    string `json:"provider"`
+	IdComponent string `json:"id_component"`
+	External    bool   `json:"external"`
 }
 
 type triplejResponse struct {
-	Items []item `json:"items"`
+	Total  int    `json:"total"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Items  []item `json:"items"`
 }
 
 type item struct {
-	Recording recording `json:"recording"`
+	Id         string    `json:"arid"`
+	PlayedTime time.Time `json:"played_time"`
+	Recording  recording `json:"recording"`
 }
 
 type recording struct {
 	Id      string   `json:"arid"`
 	Title   string   `json:"title"`
 	Artists []artist `json:"artists"`
+	// Duration is in seconds
+	Duration int       `json:"duration"`
+	Isrc     string    `json:"isrc"`
+	Releases []Release `json:"releases"`
+	Artwork  []Artwork `json:"artwork"`
+	Links    []Link    `json:"links"`
 }
 
 type artist struct {
@@ -45,51 +161,140 @@ type artist struct {
 
 type Clienter interface {
 	FetchSongsFromTriplejAPI(ctx context.Context, playlistSize int) ([]RadioSong, error)
+	FetchSongsBetween(ctx context.Context, from, to time.Time) ([]RadioSong, error)
 }
 
 //go:generate mockgen -destination=mocks/triplej.go -source=triplej.go
 
-func NewTiplejClient() Client {
-	return Client{}
+// NewTiplejClient returns a client for the plays of the given ABC station.
+// An empty station defaults to triplej.
+func NewTiplejClient(station Station, opts ...Option) Client {
+	if station == "" {
+		station = TripleJ
+	}
+	c := Client{
+		station:    station,
+		baseURL:    abcRadioAPIBaseURL,
+		userAgent:  defaultUserAgent,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	if c.timeout > 0 {
+		// copy the client so a caller supplied http.Client is left untouched
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+	}
+	return c
 }
 
-func (Client) FetchSongsFromTriplejAPI(ctx context.Context, playlistSize int) ([]RadioSong, error) {
-	var (
-		triplejResponse triplejResponse
-		songs           []RadioSong
-		abcUrl          = abcRadioAPIBaseURL + "&limit=" + strconv.Itoa(playlistSize) + "&order=desc"
-	)
-
+func (c Client) FetchSongsFromTriplejAPI(ctx context.Context, playlistSize int) ([]RadioSong, error) {
 	// Add a child span
-	_, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "FetchSongsFromTriplejAPI")
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "FetchSongsFromTriplejAPI")
 	defer childSpan.End()
 
 	if playlistSize < 0 {
 		return []RadioSong(nil), errors.New("invalid playlist size")
 	}
-	songs = make([]RadioSong, 0, playlistSize)
 
-	req, err := http.NewRequest("GET", abcUrl, nil)
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(playlistSize))
+	query.Set("order", "desc")
+
+	triplejResponse, err := c.fetchPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	songs := make([]RadioSong, 0, playlistSize)
+	return c.appendSongs(songs, triplejResponse.Items), nil
+}
+
+// RecentSongs returns the most recently played songs on the station, newest first.
+func (c Client) RecentSongs(ctx context.Context, limit int) ([]RadioSong, error) {
+	return c.FetchSongsFromTriplejAPI(ctx, limit)
+}
+
+// FetchSongsBetween returns every song played on the station between from and to,
+// newest first. The ABC plays API is paged through until the window is exhausted.
+func (c Client) FetchSongsBetween(ctx context.Context, from, to time.Time) ([]RadioSong, error) {
+	// Add a child span
+	ctx, childSpan := otel.Tracer(telemetry.TracerName).Start(ctx, "FetchSongsBetween")
+	defer childSpan.End()
+
+	if !from.Before(to) {
+		return nil, errors.New("invalid time window: from must be before to")
+	}
+
+	var songs []RadioSong
+	for offset := 0; ; {
+		query := url.Values{}
+		query.Set("from", from.UTC().Format(time.RFC3339))
+		query.Set("to", to.UTC().Format(time.RFC3339))
+		query.Set("limit", strconv.Itoa(abcPageSize))
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("order", "desc")
+
+		triplejResponse, err := c.fetchPage(ctx, query)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetching plays at offset %d failed", offset)
+		}
+
+		songs = c.appendSongs(songs, triplejResponse.Items)
+
+		// stop once the API runs out of items or we have paged past the reported total. Without
+		// a total, a short page is the last one.
+		offset += len(triplejResponse.Items)
+		if len(triplejResponse.Items) == 0 {
+			break
+		}
+		if triplejResponse.Total > 0 && offset >= triplejResponse.Total {
+			break
+		}
+		if triplejResponse.Total == 0 && len(triplejResponse.Items) < abcPageSize {
+			break
+		}
+	}
+	return songs, nil
+}
+
+// fetchPage requests a single page of plays for the client's station.
+func (c Client) fetchPage(ctx context.Context, query url.Values) (triplejResponse, error) {
+	var triplejResponse triplejResponse
+
+	query.Set("station", c.station.String())
+	abcUrl := c.baseURL + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, abcUrl, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating request to ABC Radio musicAPI failed")
+		return triplejResponse, errors.Wrap(err, "creating request to ABC Radio musicAPI failed")
 	}
+	req.Header.Set("User-Agent", c.userAgent)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "GET request to ABC Radio musicAPI failed")
+		return triplejResponse, errors.Wrap(err, "GET request to ABC Radio musicAPI failed")
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("Received non-200 response code")
+		return triplejResponse, errors.New("Received non-200 response code")
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&triplejResponse); err != nil {
-		return nil, errors.Wrap(err, "Decoding JSON response failed")
+		return triplejResponse, errors.Wrap(err, "Decoding JSON response failed")
 	}
+	return triplejResponse, nil
+}
 
-	for _, item := range triplejResponse.Items {
+// appendSongs converts plays from the ABC API into RadioSongs, skipping any without artists.
+func (c Client) appendSongs(songs []RadioSong, items []item) []RadioSong {
+	for _, item := range items {
 		var artists []string
 		rec := item.Recording
 		if len(rec.Artists) == 0 {
@@ -101,7 +306,19 @@ func (Client) FetchSongsFromTriplejAPI(ctx context.Context, playlistSize int) ([
 			artists = append(artists, artist.Name)
 		}
 
-		songs = append(songs, RadioSong{Id: rec.Id, Name: rec.Title, Artists: artists})
+		songs = append(songs, RadioSong{
+			Id:         rec.Id,
+			Name:       rec.Title,
+			Artists:    artists,
+			Station:    c.station,
+			PlayId:     item.Id,
+			PlayedTime: item.PlayedTime,
+			Duration:   time.Duration(rec.Duration) * time.Second,
+			ISRC:       cleanISRC(rec.Isrc),
+			Releases:   rec.Releases,
+			Artwork:    rec.Artwork,
+			Links:      rec.Links,
+		})
 	}
-	return songs, nil
+	return songs
 }
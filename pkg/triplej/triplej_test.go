@@ -2,14 +2,51 @@ package triplej
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
+// newPlaysServer serves a recorded ABC plays payload, trimmed to the requested limit.
+func newPlaysServer(t *testing.T, payloadFile string) *httptest.Server {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", payloadFile))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("station") != TripleJ.String() {
+			t.Errorf("Expected station %s, got: %s", TripleJ, r.URL.Query().Get("station"))
+		}
+
+		var response triplejResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			// serve malformed payloads untouched
+			_, _ = w.Write(payload)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit < len(response.Items) {
+			response.Items = response.Items[:limit]
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
 func TestFetchSongsFromTriplejAPI(t *testing.T) {
 	type args struct {
 		playlistSize int
+		payloadFile  string
 	}
 	tests := []struct {
 		name       string
@@ -20,31 +57,53 @@ func TestFetchSongsFromTriplejAPI(t *testing.T) {
 		{
 			name: "test valid playlist size",
 			args: args{
-				playlistSize: 10,
+				playlistSize: 1,
+				payloadFile:  "plays.json",
 			},
-			wantLength: 10,
+			wantLength: 1,
 			wantErr:    false,
 		},
 		{
-			name: "test another valid playlist size",
+			name: "test 0 playlist size",
 			args: args{
 				playlistSize: 0,
+				payloadFile:  "plays.json",
 			},
 			wantLength: 0,
 			wantErr:    false,
 		},
 		{
-			name: "test 0 playlist size",
+			name: "test invalid playlist size",
 			args: args{
-				playlistSize: 0,
+				playlistSize: -1,
+				payloadFile:  "plays.json",
 			},
 			wantLength: 0,
+			wantErr:    true,
+		},
+		{
+			name: "test plays missing artists are skipped",
+			args: args{
+				playlistSize: 10,
+				payloadFile:  "plays.json",
+			},
+			wantLength: 2,
 			wantErr:    false,
 		},
 		{
-			name: "test invalid playlist size",
+			name: "test duplicate plays are returned as is",
 			args: args{
-				playlistSize: -1,
+				playlistSize: 10,
+				payloadFile:  "duplicates.json",
+			},
+			wantLength: 3,
+			wantErr:    false,
+		},
+		{
+			name: "test malformed json",
+			args: args{
+				playlistSize: 10,
+				payloadFile:  "malformed.json",
 			},
 			wantLength: 0,
 			wantErr:    true,
@@ -53,7 +112,8 @@ func TestFetchSongsFromTriplejAPI(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tctx := context.Background()
-			c := NewTiplejClient()
+			server := newPlaysServer(t, tt.args.payloadFile)
+			c := NewTiplejClient(TripleJ, WithBaseURL(server.URL))
 			got, err := c.FetchSongsFromTriplejAPI(tctx, tt.args.playlistSize)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FetchSongsFromTriplejAPI() error = %v, wantErr %v", err, tt.wantErr)
@@ -62,4 +122,294 @@ func TestFetchSongsFromTriplejAPI(t *testing.T) {
 			require.Len(t, got, tt.wantLength, "FetchSongsFromTriplejAPI() got = %v, wantLength %v", got, tt.wantLength)
 		})
 	}
+
+	t.Run("test non-200 response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		c := NewTiplejClient(TripleJ, WithBaseURL(server.URL))
+		_, err := c.FetchSongsFromTriplejAPI(context.Background(), 10)
+		require.Error(t, err)
+	})
+
+	t.Run("test user agent and timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("User-Agent") != "test-agent" {
+				t.Errorf("Expected User-Agent test-agent, got: %s", r.Header.Get("User-Agent"))
+			}
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}))
+		defer server.Close()
+
+		c := NewTiplejClient(TripleJ, WithBaseURL(server.URL), WithUserAgent("test-agent"), WithTimeout(10*time.Millisecond))
+		_, err := c.FetchSongsFromTriplejAPI(context.Background(), 10)
+		require.Error(t, err, "expected the request to time out")
+	})
+}
+
+func TestNewTiplejClient(t *testing.T) {
+	httpClient := &http.Client{}
+	c := NewTiplejClient("", WithHTTPClient(httpClient), WithTimeout(time.Second))
+
+	require.Equal(t, TripleJ, c.station)
+	require.Equal(t, abcRadioAPIBaseURL, c.baseURL)
+	require.Equal(t, time.Second, c.httpClient.Timeout)
+	require.Zero(t, httpClient.Timeout, "expected the supplied http client to be left untouched")
+
+	// the timeout applies whichever order the options are given in
+	c = NewTiplejClient("", WithTimeout(time.Second), WithHTTPClient(httpClient))
+	require.Equal(t, time.Second, c.httpClient.Timeout)
+
+	c = NewTiplejClient("", WithHTTPClient(nil))
+	require.Equal(t, defaultTimeout, c.httpClient.Timeout)
+}
+
+func TestFetchSongsBetween(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("testdata", "plays.json"))
+	require.NoError(t, err)
+	var recorded triplejResponse
+	require.NoError(t, json.Unmarshal(payload, &recorded))
+
+	from := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 25, 11, 0, 0, 0, time.UTC)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if query.Get("from") != "2024-07-25T10:00:00Z" || query.Get("to") != "2024-07-25T11:00:00Z" {
+			t.Errorf("Expected window 10:00-11:00, got: %s-%s", query.Get("from"), query.Get("to"))
+		}
+
+		// serve a single play per page to force pagination
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		response := triplejResponse{Total: len(recorded.Items), Offset: offset, Limit: 1}
+		if offset < len(recorded.Items) {
+			response.Items = recorded.Items[offset : offset+1]
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	c := NewTiplejClient(TripleJ, WithBaseURL(server.URL))
+	got, err := c.FetchSongsBetween(context.Background(), from, to)
+	require.NoError(t, err)
+	require.Equal(t, len(recorded.Items), requests, "expected one request per page")
+	// the play without artists is skipped
+	require.Len(t, got, 2)
+	require.Equal(t, "Tongue Tied", got[0].Name)
+	require.Equal(t, "Espresso", got[1].Name)
+}
+
+func TestFetchSongsBetween_NoTotal(t *testing.T) {
+	from := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 25, 11, 0, 0, 0, time.UTC)
+
+	// more plays than fit on a page, served without a total
+	plays := make([]item, abcPageSize+abcPageSize/2)
+	for i := range plays {
+		plays[i] = item{Id: strconv.Itoa(i), Recording: recording{Title: "song " + strconv.Itoa(i), Artists: []artist{{Name: "artist"}}}}
+	}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		response := triplejResponse{Offset: offset, Limit: limit}
+		if offset < len(plays) {
+			response.Items = plays[offset:min(offset+limit, len(plays))]
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	c := NewTiplejClient(TripleJ, WithBaseURL(server.URL))
+	got, err := c.FetchSongsBetween(context.Background(), from, to)
+	require.NoError(t, err)
+	require.Len(t, got, len(plays))
+	require.Equal(t, 2, requests, "expected paging to stop at the short page")
+}
+
+func TestParseStation(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    Station
+		wantErr bool
+	}{
+		{name: "empty defaults to triplej", id: "", want: TripleJ},
+		{name: "double j", id: "doublej", want: DoubleJ},
+		{name: "mixed case and whitespace", id: " Unearthed ", want: Unearthed},
+		{name: "unknown station", id: "triplem", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStation(tt.id)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetchSongsBetween_InvalidWindow(t *testing.T) {
+	now := time.Now()
+	c := NewTiplejClient(TripleJ)
+
+	_, err := c.FetchSongsBetween(context.Background(), now, now.Add(-time.Hour))
+	require.Error(t, err, "expected an error when from is after to")
+}
+
+func TestClient_appendSongs(t *testing.T) {
+	payload, err := os.ReadFile("testdata/plays.json")
+	require.NoError(t, err)
+
+	var response triplejResponse
+	require.NoError(t, json.Unmarshal(payload, &response))
+
+	c := NewTiplejClient(DoubleJ)
+	got := c.appendSongs(nil, response.Items)
+
+	// the station ID play has no artists and should be skipped
+	require.Len(t, got, 2)
+
+	song := got[0]
+	require.Equal(t, "mt8dZ1N3Qa", song.Id)
+	require.Equal(t, "Tongue Tied", song.Name)
+	require.Equal(t, []string{"Marshmello", "YUNGBLUD"}, song.Artists)
+	require.Equal(t, DoubleJ, song.Station)
+	require.Equal(t, "mlgZ0oMNXo", song.PlayId)
+	require.Equal(t, time.Date(2024, 7, 25, 10, 34, 15, 0, time.UTC), song.PlayedTime.UTC())
+	require.Equal(t, 218*time.Second, song.Duration)
+	require.Len(t, song.Releases, 1)
+	require.Equal(t, "Single", song.Releases[0].Format)
+	require.Equal(t, 100, song.Releases[0].Artwork[0].Sizes[0].Width)
+	require.Len(t, song.Links, 2)
+	require.Equal(t, "spotify", song.Links[0].Provider)
+	require.Empty(t, song.ISRC)
+
+	// the ABC only sometimes knows a recording's ISRC
+	response.Items[0].Recording.Isrc = "us-um7-24-01234"
+	got = c.appendSongs(nil, response.Items)
+	require.Equal(t, "USUM72401234", got[0].ISRC)
+}
+
+func TestRadioSong_MusicBrainzRecordingId(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []Link
+		want  string
+	}{
+		{
+			name: "recording link",
+			links: []Link{{
+				Provider:    "musicbrainz",
+				Url:         "https://musicbrainz.org/recording/0b5c7c3a-0d3c-4a1e-9a41-6f4a1d8e1c55",
+				IdComponent: "0b5c7c3a-0d3c-4a1e-9a41-6f4a1d8e1c55",
+			}},
+			want: "0b5c7c3a-0d3c-4a1e-9a41-6f4a1d8e1c55",
+		},
+		{
+			name:  "recording link without id component",
+			links: []Link{{Provider: "MusicBrainz", Url: "https://musicbrainz.org/recording/0b5c7c3a/"}},
+			want:  "0b5c7c3a",
+		},
+		{
+			name:  "artist link is ignored",
+			links: []Link{{Provider: "musicbrainz", Url: "https://musicbrainz.org/artist/1a2b3c", IdComponent: "1a2b3c"}},
+			want:  "",
+		},
+		{
+			name:  "other providers are ignored",
+			links: []Link{{Provider: "spotify", Url: "https://open.spotify.com/track/4Y2W4bRg1nZLkvZgZpFbTw"}},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			song := RadioSong{Links: tt.links}
+			require.Equal(t, tt.want, song.MusicBrainzRecordingId())
+		})
+	}
+}
+
+func TestRadioSong_SpotifyTrackId(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []Link
+		want  string
+	}{
+		{
+			name: "track url",
+			links: []Link{{
+				Provider: "spotify",
+				Url:      "https://open.spotify.com/track/4Y2W4bRg1nZLkvZgZpFbTw?si=abc",
+			}},
+			want: "4Y2W4bRg1nZLkvZgZpFbTw",
+		},
+		{
+			name: "localised track url",
+			links: []Link{{
+				Provider: "Spotify",
+				Url:      "https://open.spotify.com/intl-en/track/4Y2W4bRg1nZLkvZgZpFbTw",
+			}},
+			want: "4Y2W4bRg1nZLkvZgZpFbTw",
+		},
+		{
+			name:  "lookalike host is ignored",
+			links: []Link{{Provider: "spotify", Url: "https://evilspotify.com/track/4Y2W4bRg1nZLkvZgZpFbTw"}},
+			want:  "",
+		},
+		{
+			name:  "spotify uri",
+			links: []Link{{Provider: "spotify", Url: "spotify:track:4Y2W4bRg1nZLkvZgZpFbTw"}},
+			want:  "4Y2W4bRg1nZLkvZgZpFbTw",
+		},
+		{
+			name:  "uri with a path is ignored",
+			links: []Link{{Provider: "spotify", Url: "spotify:track:../me"}},
+			want:  "",
+		},
+		{
+			name:  "url with an invalid id is ignored",
+			links: []Link{{Provider: "spotify", Url: "https://open.spotify.com/track/..%2Fme"}},
+			want:  "",
+		},
+		{
+			name:  "url with a short id is ignored",
+			links: []Link{{Provider: "spotify", Url: "https://open.spotify.com/track/4Y2W4bRg1nZ"}},
+			want:  "",
+		},
+		{
+			name:  "album link is ignored",
+			links: []Link{{Provider: "spotify", Url: "https://open.spotify.com/album/1A2B3C"}},
+			want:  "",
+		},
+		{
+			name:  "other providers are ignored",
+			links: []Link{{Provider: "musicbrainz", Url: "https://musicbrainz.org/recording/0b5c7c3a"}},
+			want:  "",
+		},
+		{
+			name: "no links",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			song := RadioSong{Links: tt.links}
+			require.Equal(t, tt.want, song.SpotifyTrackId())
+		})
+	}
 }
@@ -0,0 +1,174 @@
+package triplej
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	defaultMinPollInterval = 5 * time.Second
+	defaultMaxPollInterval = time.Minute
+	// defaultWatchLookback is the number of recent plays requested on each poll
+	defaultWatchLookback = 5
+)
+
+// Watcher polls the ABC plays API and streams each new play exactly once.
+type Watcher struct {
+	client      Clienter
+	minInterval time.Duration
+	maxInterval time.Duration
+	lookback    int
+	now         func() time.Time
+}
+
+// WatcherOption configures optional behaviour of a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithPollInterval bounds how often the watcher polls. It polls at minInterval while a song
+// change is due and backs off towards maxInterval while nothing new is being played.
+func WithPollInterval(minInterval, maxInterval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.minInterval = minInterval
+		w.maxInterval = maxInterval
+	}
+}
+
+// WithLookback sets how many recent plays are requested on each poll. It should cover
+// the most songs that could air between two polls.
+func WithLookback(lookback int) WatcherOption {
+	return func(w *Watcher) {
+		w.lookback = lookback
+	}
+}
+
+func NewWatcher(client Clienter, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		client:      client,
+		minInterval: defaultMinPollInterval,
+		maxInterval: defaultMaxPollInterval,
+		lookback:    defaultWatchLookback,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Watch starts polling in the background and returns a channel of new plays, oldest first.
+// The plays already on air when Watch is called are sent first. The channel is closed once
+// ctx is cancelled.
+func (w *Watcher) Watch(ctx context.Context) <-chan RadioSong {
+	plays := make(chan RadioSong)
+	go func() {
+		defer close(plays)
+		w.run(ctx, plays)
+	}()
+	return plays
+}
+
+func (w *Watcher) run(ctx context.Context, plays chan<- RadioSong) {
+	var (
+		seen      = make(map[string]bool)
+		latest    RadioSong
+		idlePolls int
+	)
+
+	for {
+		songs, err := w.client.FetchSongsFromTriplejAPI(ctx, w.lookback)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("Warning: polling for new plays failed:", err)
+		}
+
+		// songs are returned newest first, so walk backwards to emit them in the order they aired
+		var newPlays int
+		for i := len(songs) - 1; i >= 0; i-- {
+			key := songs[i].PlayKey()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			newPlays++
+			latest = songs[i]
+
+			select {
+			case plays <- songs[i]:
+			case <-ctx.Done():
+				return
+			}
+		}
+		seen = pruneSeen(seen, songs)
+
+		idlePolls = w.countIdlePolls(latest, newPlays, idlePolls)
+
+		timer := time.NewTimer(w.nextInterval(latest, idlePolls))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// countIdlePolls counts the polls in a row that found nothing new. Polls made while the
+// latest song is still expected to be on air aren't idle, so a song change that is due
+// is polled for at the minimum interval rather than backed off from.
+func (w *Watcher) countIdlePolls(latest RadioSong, newPlays, idlePolls int) int {
+	if newPlays > 0 || w.remaining(latest) > 0 {
+		return 0
+	}
+	return idlePolls + 1
+}
+
+// nextInterval waits out the rest of the song on air when its duration is known,
+// otherwise it polls at the minimum interval and doubles it each time nothing new has aired.
+func (w *Watcher) nextInterval(latest RadioSong, idlePolls int) time.Duration {
+	if remaining := w.remaining(latest); remaining > 0 {
+		return w.clamp(remaining)
+	}
+
+	interval := w.minInterval
+	for i := 1; i < idlePolls && interval < w.maxInterval; i++ {
+		interval *= 2
+	}
+	return w.clamp(interval)
+}
+
+// remaining is how long the song is expected to stay on air, zero when it should have
+// finished or its duration isn't known.
+func (w *Watcher) remaining(song RadioSong) time.Duration {
+	if song.Duration <= 0 || song.PlayedTime.IsZero() {
+		return 0
+	}
+	return max(song.PlayedTime.Add(song.Duration).Sub(w.now()), 0)
+}
+
+func (w *Watcher) clamp(interval time.Duration) time.Duration {
+	if interval < w.minInterval {
+		return w.minInterval
+	}
+	if interval > w.maxInterval {
+		return w.maxInterval
+	}
+	return interval
+}
+
+// pruneSeen forgets plays that have dropped out of the polled window so the set doesn't grow forever.
+func pruneSeen(seen map[string]bool, songs []RadioSong) map[string]bool {
+	if len(songs) == 0 {
+		return seen
+	}
+	pruned := make(map[string]bool, len(songs))
+	for _, song := range songs {
+		key := song.PlayKey()
+		if seen[key] {
+			pruned[key] = true
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,149 @@
+package triplej
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient returns each of its polls in turn, repeating the last one once they run out.
+type fakeClient struct {
+	mu    sync.Mutex
+	polls [][]RadioSong
+	calls int
+}
+
+func (f *fakeClient) FetchSongsFromTriplejAPI(_ context.Context, _ int) ([]RadioSong, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	poll := f.polls[min(f.calls, len(f.polls)-1)]
+	f.calls++
+	return poll, nil
+}
+
+func (f *fakeClient) FetchSongsBetween(_ context.Context, _, _ time.Time) ([]RadioSong, error) {
+	return nil, nil
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	first := RadioSong{Id: "a", PlayId: "play1", Name: "first"}
+	second := RadioSong{Id: "b", PlayId: "play2", Name: "second"}
+	// the same recording aired again is a new play
+	replay := RadioSong{Id: "a", PlayId: "play3", Name: "first"}
+
+	client := &fakeClient{polls: [][]RadioSong{
+		{first},
+		{second, first},
+		{second, first},
+		{replay, second, first},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := NewWatcher(client, WithPollInterval(time.Millisecond, 2*time.Millisecond))
+	plays := w.Watch(ctx)
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case song := <-plays:
+			got = append(got, song.PlayId)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for plays, got %v", got)
+		}
+	}
+	require.Equal(t, []string{"play1", "play2", "play3"}, got)
+
+	// no play is emitted twice while the watcher keeps polling
+	select {
+	case song := <-plays:
+		t.Fatalf("unexpected repeat play %v", song)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-plays:
+		require.False(t, ok, "expected the channel to be closed after cancel")
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not stop after cancel")
+	}
+}
+
+func TestWatcher_nextInterval(t *testing.T) {
+	now := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	w := NewWatcher(&fakeClient{}, WithPollInterval(5*time.Second, time.Minute))
+	w.now = func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		latest    RadioSong
+		idlePolls int
+		want      time.Duration
+	}{
+		{
+			name:   "waits for the song on air to finish",
+			latest: RadioSong{PlayedTime: now.Add(-time.Minute), Duration: 90 * time.Second},
+			want:   30 * time.Second,
+		},
+		{
+			name:   "long songs are capped at the max interval",
+			latest: RadioSong{PlayedTime: now, Duration: 10 * time.Minute},
+			want:   time.Minute,
+		},
+		{
+			name:      "overdue song change polls at the min interval",
+			latest:    RadioSong{PlayedTime: now.Add(-5 * time.Minute), Duration: 3 * time.Minute},
+			idlePolls: 1,
+			want:      5 * time.Second,
+		},
+		{
+			name:      "backs off while nothing new airs",
+			idlePolls: 3,
+			want:      20 * time.Second,
+		},
+		{
+			name:      "back off is capped at the max interval",
+			idlePolls: 10,
+			want:      time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, w.nextInterval(tt.latest, tt.idlePolls))
+		})
+	}
+}
+
+func TestWatcher_countIdlePolls(t *testing.T) {
+	start := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	now := start
+	w := NewWatcher(&fakeClient{}, WithPollInterval(5*time.Second, time.Minute))
+	w.now = func() time.Time { return now }
+
+	t.Run("polls while the song is on air aren't idle", func(t *testing.T) {
+		song := RadioSong{PlayedTime: start, Duration: 4 * time.Minute}
+		idlePolls := w.countIdlePolls(song, 1, 0)
+		// poll the way the watcher does until the song should have finished
+		for now.Before(start.Add(song.Duration)) {
+			now = now.Add(w.nextInterval(song, idlePolls))
+			idlePolls = w.countIdlePolls(song, 0, idlePolls)
+		}
+		require.Equal(t, 1, idlePolls)
+		require.Equal(t, 5*time.Second, w.nextInterval(song, idlePolls))
+	})
+
+	t.Run("counts polls that find nothing new once the song is overdue", func(t *testing.T) {
+		song := RadioSong{PlayedTime: now.Add(-5 * time.Minute), Duration: 3 * time.Minute}
+		require.Equal(t, 3, w.countIdlePolls(song, 0, 2))
+		require.Equal(t, 0, w.countIdlePolls(song, 1, 2))
+	})
+
+	t.Run("counts polls that find nothing new without a duration", func(t *testing.T) {
+		require.Equal(t, 3, w.countIdlePolls(RadioSong{PlayedTime: now}, 0, 2))
+	})
+}